@@ -1,10 +1,13 @@
 package log
 
-import "os"
+import (
+	"context"
+	"os"
+)
 
 var (
 	// 级别
-	levels = []string{"[D] ", "[I] ", "[W] ", "[E] ", "[P] "}
+	levels = []string{"[D] ", "[I] ", "[W] ", "[E] ", "[P] ", "[F] "}
 	// DefaultLogger 默认
 	DefaultLogger *Logger
 )
@@ -20,6 +23,11 @@ var (
 	DebugfTrace      func(traceID, format string, args ...any)
 	DebugDepthTrace  func(depth int, traceID string, args ...any)
 	DebugfDepthTrace func(depth int, traceID, format string, args ...any)
+	DebugFields      func(msg string, fields ...Field)
+	DebugFieldsTrace func(traceID, msg string, fields ...Field)
+	DebugCtx         func(ctx context.Context, args ...any)
+	DebugfCtx        func(ctx context.Context, format string, args ...any)
+	DebugFieldsCtx   func(ctx context.Context, msg string, fields ...Field)
 	// Info
 	Info            func(args ...any)
 	Infof           func(format string, args ...any)
@@ -29,6 +37,11 @@ var (
 	InfofTrace      func(traceID, format string, args ...any)
 	InfoDepthTrace  func(depth int, traceID string, args ...any)
 	InfofDepthTrace func(depth int, traceID, format string, args ...any)
+	InfoFields      func(msg string, fields ...Field)
+	InfoFieldsTrace func(traceID, msg string, fields ...Field)
+	InfoCtx         func(ctx context.Context, args ...any)
+	InfofCtx        func(ctx context.Context, format string, args ...any)
+	InfoFieldsCtx   func(ctx context.Context, msg string, fields ...Field)
 	// Warn
 	Warn            func(args ...any)
 	Warnf           func(format string, args ...any)
@@ -38,6 +51,11 @@ var (
 	WarnfTrace      func(traceID, format string, args ...any)
 	WarnDepthTrace  func(depth int, traceID string, args ...any)
 	WarnfDepthTrace func(depth int, traceID, format string, args ...any)
+	WarnFields      func(msg string, fields ...Field)
+	WarnFieldsTrace func(traceID, msg string, fields ...Field)
+	WarnCtx         func(ctx context.Context, args ...any)
+	WarnfCtx        func(ctx context.Context, format string, args ...any)
+	WarnFieldsCtx   func(ctx context.Context, msg string, fields ...Field)
 	// Error
 	Error            func(args ...any)
 	Errorf           func(format string, args ...any)
@@ -47,16 +65,25 @@ var (
 	ErrorfTrace      func(traceID, format string, args ...any)
 	ErrorDepthTrace  func(depth int, traceID string, args ...any)
 	ErrorfDepthTrace func(depth int, traceID, format string, args ...any)
+	ErrorFields      func(msg string, fields ...Field)
+	ErrorFieldsTrace func(traceID, msg string, fields ...Field)
+	ErrorCtx         func(ctx context.Context, args ...any)
+	ErrorfCtx        func(ctx context.Context, format string, args ...any)
+	ErrorFieldsCtx   func(ctx context.Context, msg string, fields ...Field)
+	// Panic
+	Panic       func(args ...any)
+	Panicf      func(format string, args ...any)
+	PanicTrace  func(traceID string, args ...any)
+	PanicfTrace func(traceID, format string, args ...any)
+	// Fatal
+	Fatal       func(args ...any)
+	Fatalf      func(format string, args ...any)
+	FatalTrace  func(traceID string, args ...any)
+	FatalfTrace func(traceID, format string, args ...any)
 	// Recover
 	Recover func(recover any)
-)
-
-const (
-	debugLevel = iota
-	infoLevel
-	warnLevel
-	errorLevel
-	panicLevel
+	// SetLevel 设置默认 Logger 的最小级别
+	SetLevel func(level Level)
 )
 
 func init() {
@@ -75,6 +102,11 @@ func SetLogger(lg *Logger) {
 	DebugfTrace = DefaultLogger.DebugfTrace
 	DebugDepthTrace = DefaultLogger.DebugDepthTrace
 	DebugfDepthTrace = DefaultLogger.DebugfDepthTrace
+	DebugFields = DefaultLogger.DebugFields
+	DebugFieldsTrace = DefaultLogger.DebugFieldsTrace
+	DebugCtx = DefaultLogger.DebugCtx
+	DebugfCtx = DefaultLogger.DebugfCtx
+	DebugFieldsCtx = DefaultLogger.DebugFieldsCtx
 	// Info
 	Info = DefaultLogger.Info
 	Infof = DefaultLogger.Infof
@@ -84,6 +116,11 @@ func SetLogger(lg *Logger) {
 	InfofTrace = DefaultLogger.InfofTrace
 	InfoDepthTrace = DefaultLogger.InfoDepthTrace
 	InfofDepthTrace = DefaultLogger.InfofDepthTrace
+	InfoFields = DefaultLogger.InfoFields
+	InfoFieldsTrace = DefaultLogger.InfoFieldsTrace
+	InfoCtx = DefaultLogger.InfoCtx
+	InfofCtx = DefaultLogger.InfofCtx
+	InfoFieldsCtx = DefaultLogger.InfoFieldsCtx
 	// Warn
 	Warn = DefaultLogger.Warn
 	Warnf = DefaultLogger.Warnf
@@ -93,6 +130,11 @@ func SetLogger(lg *Logger) {
 	WarnfTrace = DefaultLogger.WarnfTrace
 	WarnDepthTrace = DefaultLogger.WarnDepthTrace
 	WarnfDepthTrace = DefaultLogger.WarnfDepthTrace
+	WarnFields = DefaultLogger.WarnFields
+	WarnFieldsTrace = DefaultLogger.WarnFieldsTrace
+	WarnCtx = DefaultLogger.WarnCtx
+	WarnfCtx = DefaultLogger.WarnfCtx
+	WarnFieldsCtx = DefaultLogger.WarnFieldsCtx
 	// Error
 	Error = DefaultLogger.Error
 	Errorf = DefaultLogger.Errorf
@@ -102,6 +144,23 @@ func SetLogger(lg *Logger) {
 	ErrorfTrace = DefaultLogger.ErrorfTrace
 	ErrorDepthTrace = DefaultLogger.ErrorDepthTrace
 	ErrorfDepthTrace = DefaultLogger.ErrorfDepthTrace
+	ErrorFields = DefaultLogger.ErrorFields
+	ErrorFieldsTrace = DefaultLogger.ErrorFieldsTrace
+	ErrorCtx = DefaultLogger.ErrorCtx
+	ErrorfCtx = DefaultLogger.ErrorfCtx
+	ErrorFieldsCtx = DefaultLogger.ErrorFieldsCtx
+	// Panic
+	Panic = DefaultLogger.Panic
+	Panicf = DefaultLogger.Panicf
+	PanicTrace = DefaultLogger.PanicTrace
+	PanicfTrace = DefaultLogger.PanicfTrace
+	// Fatal
+	Fatal = DefaultLogger.Fatal
+	Fatalf = DefaultLogger.Fatalf
+	FatalTrace = DefaultLogger.FatalTrace
+	FatalfTrace = DefaultLogger.FatalfTrace
 	// Recover
 	Recover = DefaultLogger.Recover
+	// Level
+	SetLevel = DefaultLogger.SetLevel
 }
@@ -0,0 +1,261 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// 默认的队列大小
+	defaultAsyncQueueSize = 10000
+)
+
+var (
+	errAsyncClosed = errors.New("async has been closed")
+	// 缓存池
+	asyncLinePool = sync.Pool{
+		New: func() any {
+			return new(asyncLine)
+		},
+	}
+)
+
+// Flusher 是可以立即同步数据的输出，比如 File
+type Flusher interface {
+	Flush() error
+}
+
+// AsyncConfig 是 NewAsync 的参数。
+type AsyncConfig struct {
+	// 队列的最大行数，默认是 10000
+	QueueSize int `json:"queueSize" yaml:"queueSize" validate:"omitempty,min=1"`
+	// 队列满的时候，block 阻塞，newest 丢弃最新的，oldest 丢弃最旧的，
+	// level 丢弃级别小于 DropLevel 的，其他阻塞，默认是 block
+	Overflow string `json:"overflow" yaml:"overflow" validate:"omitempty,oneof=block newest oldest level"`
+	// Overflow 是 level 时使用，debug/info/warn/error ，默认是 info
+	DropLevel string `json:"dropLevel" yaml:"dropLevel"`
+}
+
+// asyncLine 是队列中的一行日志
+type asyncLine struct {
+	b []byte
+	// 级别，没有 Entry 的是 -1
+	level Level
+	// 复制的 Entry
+	entry *Entry
+}
+
+// NewAsync 返回一个 Async 实例，w 是真正的输出。
+func NewAsync(w io.Writer, conf *AsyncConfig) (*Async, error) {
+	a := new(Async)
+	a.w = w
+	queueSize := conf.QueueSize
+	if queueSize < 1 {
+		queueSize = defaultAsyncQueueSize
+	}
+	a.queue = make(chan *asyncLine, queueSize)
+	a.overflow = conf.Overflow
+	a.dropLevel = InfoLevel
+	if conf.DropLevel != "" {
+		level, err := ParseLevel(conf.DropLevel)
+		if err != nil {
+			return nil, err
+		}
+		a.dropLevel = level
+	}
+	a.exit = make(chan struct{})
+	a.flush = make(chan chan struct{})
+	// 启动输出协程
+	a.wait.Add(1)
+	go a.writeLoop()
+	return a, nil
+}
+
+// Async 实现了 io.Writer 和 EntryWriter 接口，可以作为 Logger 的输出。
+// 日志复制到有界的队列，由后台协程写入真正的输出，调用者不会被慢的输出阻塞。
+// 队列满的时候按照 Overflow 处理，Close 和 Flush 保证队列中的日志全部写入。
+type Async struct {
+	lock sync.RWMutex
+	wait sync.WaitGroup
+	// 退出协程通知
+	exit chan struct{}
+	// Flush 请求
+	flush chan chan struct{}
+	// 是否已关闭标志
+	closed bool
+	// 真正的输出
+	w io.Writer
+	// 队列
+	queue chan *asyncLine
+	// 队列满的时候的处理
+	overflow string
+	// overflow 是 level 的时候，丢弃小于它的
+	dropLevel Level
+	// 丢弃的行数
+	dropped atomic.Uint64
+}
+
+// Write 实现 io.Writer 。
+func (a *Async) Write(b []byte) (int, error) {
+	line := asyncLinePool.Get().(*asyncLine)
+	line.b = append(line.b[:0], b...)
+	line.level = -1
+	return a.enqueue(line)
+}
+
+// WriteEntry 实现 EntryWriter ，如果真正的输出也是 EntryWriter ，会复制 Entry 。
+func (a *Async) WriteEntry(e *Entry, b []byte) (int, error) {
+	line := asyncLinePool.Get().(*asyncLine)
+	line.b = append(line.b[:0], b...)
+	line.level = e.Level
+	if _, ok := a.w.(EntryWriter); ok {
+		line.entry = &Entry{
+			PC:      e.CallerPC(),
+			Level:   e.Level,
+			Name:    e.Name,
+			Trace:   e.Trace,
+			Message: append([]byte(nil), e.Message...),
+			Bound:   e.Bound,
+			Fields:  append([]Field(nil), e.Fields...),
+			spans:   e.spans,
+		}
+	}
+	return a.enqueue(line)
+}
+
+// Dropped 返回丢弃的行数
+func (a *Async) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// enqueue 添加到队列，满了按照 overflow 处理
+func (a *Async) enqueue(line *asyncLine) (int, error) {
+	n := len(line.b)
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if a.closed {
+		a.putLine(line)
+		return 0, errAsyncClosed
+	}
+	select {
+	case a.queue <- line:
+		return n, nil
+	default:
+	}
+	// 队列满了
+	switch a.overflow {
+	case "newest":
+		a.dropped.Add(1)
+		a.putLine(line)
+		return n, nil
+	case "oldest":
+		for {
+			select {
+			case a.queue <- line:
+				return n, nil
+			default:
+			}
+			select {
+			case old := <-a.queue:
+				a.dropped.Add(1)
+				a.putLine(old)
+			default:
+			}
+		}
+	case "level":
+		if line.level >= 0 && line.level < a.dropLevel {
+			a.dropped.Add(1)
+			a.putLine(line)
+			return n, nil
+		}
+	}
+	// 阻塞
+	a.queue <- line
+	return n, nil
+}
+
+// Flush 实现 Flusher ，等待队列中已有的日志写入，如果真正的输出是 Flusher ，调用它的 Flush 。
+func (a *Async) Flush() error {
+	done := make(chan struct{})
+	a.lock.RLock()
+	if a.closed {
+		a.lock.RUnlock()
+		return errAsyncClosed
+	}
+	a.flush <- done
+	a.lock.RUnlock()
+	<-done
+	return nil
+}
+
+// Close 实现 io.Closer ，等待队列中所有的日志写入，
+// 如果真正的输出是 io.Closer ，关闭它。
+func (a *Async) Close() error {
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return errAsyncClosed
+	}
+	a.closed = true
+	a.lock.Unlock()
+	// 结束协程通知。
+	close(a.exit)
+	// 等待退出。
+	a.wait.Wait()
+	// 关闭输出
+	if c, ok := a.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// writeLoop 运行在一个协程中，写入真正的输出，退出前写完队列中的日志。
+func (a *Async) writeLoop() {
+	defer a.wait.Done()
+	for {
+		select {
+		case line := <-a.queue:
+			a.write(line)
+		case done := <-a.flush:
+			a.writeAll()
+			if f, ok := a.w.(Flusher); ok {
+				f.Flush()
+			}
+			close(done)
+		case <-a.exit:
+			// 关闭之后不会再有新的日志
+			a.writeAll()
+			return
+		}
+	}
+}
+
+// writeAll 写入队列中所有的日志
+func (a *Async) writeAll() {
+	for {
+		select {
+		case line := <-a.queue:
+			a.write(line)
+		default:
+			return
+		}
+	}
+}
+
+// write 写入一行日志到真正的输出
+func (a *Async) write(line *asyncLine) {
+	if line.entry != nil {
+		a.w.(EntryWriter).WriteEntry(line.entry, line.b)
+	} else {
+		a.w.Write(line.b)
+	}
+	a.putLine(line)
+}
+
+// putLine 回收
+func (a *Async) putLine(line *asyncLine) {
+	line.entry = nil
+	asyncLinePool.Put(line)
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testEntryWriter 保存 WriteEntry 的数据
+type testEntryWriter struct {
+	lock    sync.Mutex
+	lines   []string
+	callers []string
+	flushed int
+	closed  bool
+}
+
+func (w *testEntryWriter) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	w.lines = append(w.lines, string(b))
+	w.lock.Unlock()
+	return len(b), nil
+}
+
+func (w *testEntryWriter) WriteEntry(e *Entry, b []byte) (int, error) {
+	path, _, _ := e.Caller()
+	w.lock.Lock()
+	w.lines = append(w.lines, string(b))
+	w.callers = append(w.callers, trimPath(path))
+	w.lock.Unlock()
+	return len(b), nil
+}
+
+func (w *testEntryWriter) Flush() error {
+	w.lock.Lock()
+	w.flushed++
+	w.lock.Unlock()
+	return nil
+}
+
+func (w *testEntryWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func Test_Async(t *testing.T) {
+	w := new(testEntryWriter)
+	a, err := NewAsync(w, &AsyncConfig{QueueSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := NewLogger(a, DefaultHeader, "")
+	for i := 0; i < 100; i++ {
+		lg.Info(i)
+	}
+	a.Flush()
+	w.lock.Lock()
+	if len(w.lines) != 100 || w.flushed != 1 {
+		t.Fatal(len(w.lines), w.flushed)
+	}
+	for i, line := range w.lines {
+		if !strings.HasSuffix(line, " "+strconv.Itoa(i)+"\n") || w.callers[i] != "async_test.go" {
+			t.Fatal(line, w.callers[i])
+		}
+	}
+	w.lock.Unlock()
+	lg.Info(100)
+	a.Close()
+	if len(w.lines) != 101 || !w.closed || a.Dropped() != 0 {
+		t.FailNow()
+	}
+	if _, err := a.Write(nil); err != errAsyncClosed {
+		t.FailNow()
+	}
+}
+
+func Test_AsyncOverflow(t *testing.T) {
+	// 没有输出协程
+	a := &Async{queue: make(chan *asyncLine, 2), overflow: "newest"}
+	for i := 0; i < 3; i++ {
+		a.Write([]byte(strconv.Itoa(i)))
+	}
+	if a.Dropped() != 1 || string((<-a.queue).b) != "0" {
+		t.FailNow()
+	}
+	// 丢弃最旧的
+	a = &Async{queue: make(chan *asyncLine, 2), overflow: "oldest"}
+	for i := 0; i < 3; i++ {
+		a.Write([]byte(strconv.Itoa(i)))
+	}
+	if a.Dropped() != 1 || string((<-a.queue).b) != "1" {
+		t.FailNow()
+	}
+	// 丢弃小于 warn 的
+	a = &Async{queue: make(chan *asyncLine, 1), overflow: "level", dropLevel: WarnLevel}
+	a.WriteEntry(&Entry{Level: ErrorLevel}, []byte("0"))
+	a.WriteEntry(&Entry{Level: InfoLevel}, []byte("1"))
+	if a.Dropped() != 1 || string((<-a.queue).b) != "0" {
+		t.FailNow()
+	}
+}
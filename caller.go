@@ -0,0 +1,177 @@
+package log
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+var (
+	// ModulePath 是主模块的路径，输出包路径的时候去掉这个前缀，默认从编译信息中读取
+	ModulePath = mainModulePath()
+	// pc 和 *Frame 的缓存
+	frameCache sync.Map
+	// goroutine 的前缀
+	goroutinePrefix = []byte("goroutine ")
+	// FuncNameHeader 使用的格式
+	funcNameFormat = &CallerFormat{Func: true}
+)
+
+// mainModulePath 返回编译信息中主模块的路径
+func mainModulePath() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	return info.Main.Path
+}
+
+// Frame 是调用者的信息
+type Frame struct {
+	// 文件路径
+	File string
+	// 行号
+	Line int
+	// 完整的函数名，比如 github.com/a/b.(*T).Func
+	Function string
+	// 包路径，比如 github.com/a/b
+	Package string
+	// 去掉包路径的函数名，比如 (*T).Func
+	Func string
+}
+
+// pcFrame 返回 pc 的调用者信息，使用缓存，同一个调用的地方只解析一次
+func pcFrame(pc uintptr) *Frame {
+	if v, ok := frameCache.Load(pc); ok {
+		return v.(*Frame)
+	}
+	frames := runtime.CallersFrames([]uintptr{pc})
+	rf, _ := frames.Next()
+	f := &Frame{
+		File:     rf.File,
+		Line:     rf.Line,
+		Function: rf.Function,
+	}
+	f.Package, f.Func = splitFunction(rf.Function)
+	if f.File == "" {
+		f.File = "???"
+		f.Line = -1
+	}
+	if f.Func == "" {
+		f.Func = "???"
+	}
+	v, _ := frameCache.LoadOrStore(pc, f)
+	return v.(*Frame)
+}
+
+// splitFunction 将 github.com/a/b.(*T).Func 分成 github.com/a/b 和 (*T).Func
+func splitFunction(name string) (string, string) {
+	i := strings.LastIndexByte(name, '/')
+	if i < 0 {
+		i = 0
+	}
+	j := strings.IndexByte(name[i:], '.')
+	if j < 0 {
+		return "", name
+	}
+	return name[:i+j], name[i+j+1:]
+}
+
+// trimPackage 返回相对于 ModulePath 的包路径，主模块的根包使用模块的最后一段
+func trimPackage(pkg string) string {
+	if ModulePath == "" {
+		return pkg
+	}
+	if pkg == ModulePath {
+		if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+			return pkg[i+1:]
+		}
+		return pkg
+	}
+	if strings.HasPrefix(pkg, ModulePath) && pkg[len(ModulePath)] == '/' {
+		return pkg[len(ModulePath)+1:]
+	}
+	return pkg
+}
+
+// callerFrame 返回调用者的信息，如果有 pc 使用 pc ，否则使用 depth
+// depth 和 FormatHeader 的参数相同
+func (l *Log) callerFrame(depth int) *Frame {
+	pc := l.pc
+	if pc == 0 {
+		var pcs [1]uintptr
+		runtime.Callers(depth+2, pcs[:])
+		pc = pcs[0]
+	}
+	return pcFrame(pc)
+}
+
+// goroutineID 返回当前 goroutine 的 ID ，从 runtime.Stack 中解析
+func goroutineID() int {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	if len(b) <= len(goroutinePrefix) {
+		return -1
+	}
+	b = b[len(goroutinePrefix):]
+	id := 0
+	for i := 0; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+		id = id*10 + int(b[i]-'0')
+	}
+	return id
+}
+
+// CallerFormat 设置日志头中调用者的信息，
+// 输出 "时间 file:line package.func g=goroutine"
+type CallerFormat struct {
+	// 时间的格式，nil 使用 FormatTime
+	Time *TimeFormatter
+	// 是否使用完整的文件路径，否则只有文件名
+	FullPath bool
+	// 是否输出函数名
+	Func bool
+	// 函数名前面是否输出相对于 ModulePath 的包路径
+	Package bool
+	// 是否输出 goroutine ID ，需要调用 runtime.Stack ，比较慢
+	Goroutine bool
+}
+
+// Header 是 FormatHeader
+func (cf *CallerFormat) Header(log *Log, depth int) {
+	// 时间
+	if cf.Time != nil {
+		cf.Time.Format(log)
+	} else {
+		FormatTime(log)
+	}
+	// file:line
+	f := log.callerFrame(depth)
+	log.b = append(log.b, ' ')
+	if cf.FullPath {
+		log.b = append(log.b, f.File...)
+	} else {
+		log.b = append(log.b, trimPath(f.File)...)
+	}
+	log.b = append(log.b, ':')
+	log.Int(f.Line)
+	// package.func
+	if cf.Func {
+		log.b = append(log.b, ' ')
+		if cf.Package && f.Package != "" {
+			log.b = append(log.b, trimPackage(f.Package)...)
+			log.b = append(log.b, '.')
+		}
+		log.b = append(log.b, f.Func...)
+	}
+	// goroutine
+	if cf.Goroutine {
+		log.b = append(log.b, " g="...)
+		log.Int(goroutineID())
+	}
+}
+
+// FuncNameHeader 输出 2006-01-02 15:04:05.000000000 fileName:fileLine func
+func FuncNameHeader(log *Log, depth int) {
+	funcNameFormat.Header(log, depth+1)
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testCallerT struct{}
+
+// log 输出一行日志，返回它的行号
+func (*testCallerT) log(lg *Logger) int {
+	line := nextLine()
+	lg.Info("a")
+	return line
+}
+
+func Test_CallerFormat(t *testing.T) {
+	var l Log
+	lg := NewLogger(&l, FuncNameHeader, "")
+	line := nextLine()
+	lg.Info("a")
+	if !strings.Contains(string(l.b), " caller_test.go:"+strconv.Itoa(line)+" Test_CallerFormat a") {
+		t.Fatal(string(l.b))
+	}
+	// 包，方法和 goroutine
+	l.Reset()
+	lg.Header = (&CallerFormat{Func: true, Package: true, Goroutine: true}).Header
+	line = new(testCallerT).log(lg)
+	if !strings.Contains(string(l.b), " caller_test.go:"+strconv.Itoa(line)+" log.(*testCallerT).log g=") {
+		t.Fatal(string(l.b))
+	}
+	// 缓存
+	l.Reset()
+	new(testCallerT).log(lg)
+	if !strings.Contains(string(l.b), " caller_test.go:"+strconv.Itoa(line)+" log.(*testCallerT).log g=") {
+		t.Fatal(string(l.b))
+	}
+}
+
+func Test_splitFunction(t *testing.T) {
+	for _, c := range [][3]string{
+		{"github.com/a/b.(*T).Func", "github.com/a/b", "(*T).Func"},
+		{"github.com/a/b.Func.func1", "github.com/a/b", "Func.func1"},
+		{"main.main", "main", "main"},
+	} {
+		pkg, fn := splitFunction(c[0])
+		if pkg != c[1] || fn != c[2] {
+			t.Fatal(c, pkg, fn)
+		}
+	}
+	modulePath := ModulePath
+	defer func() { ModulePath = modulePath }()
+	ModulePath = "github.com/a/b"
+	if trimPackage("github.com/a/b/c/d") != "c/d" ||
+		trimPackage("github.com/a/b") != "b" ||
+		trimPackage("github.com/a/bc") != "github.com/a/bc" {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"io"
+	"os"
+)
+
+// 终端颜色
+const (
+	colorReset   = "\x1b[0m"
+	colorBold    = "\x1b[1m"
+	colorDim     = "\x1b[2m"
+	colorTrace   = "\x1b[1;36m"
+	colorDebug   = "\x1b[36m"
+	colorInfo    = "\x1b[32m"
+	colorWarn    = "\x1b[33m"
+	colorError   = "\x1b[31m"
+	colorFailure = "\x1b[1;35m"
+)
+
+var (
+	// 级别的颜色
+	levelColors = []string{
+		colorDebug,
+		colorInfo,
+		colorWarn,
+		colorError,
+		colorFailure,
+		colorFailure,
+	}
+)
+
+// ColorEncoder 和 TextEncoder 的格式一样，使用 ANSI 颜色区分级别，
+// 名称加粗，时间和调用变暗，追踪高亮，只适合输出到终端
+type ColorEncoder struct{}
+
+// Encode 实现 Encoder
+func (ColorEncoder) Encode(l *Log, e *Entry) {
+	start := len(l.b)
+	appendText(l, e, e.Depth+1)
+	// 复制出来再着色
+	m := logPool.Get().(*Log)
+	m.b = append(m.b[:0], l.b[start:]...)
+	l.b = l.b[:start]
+	colorizeText(l, e, m.b)
+	logPool.Put(m)
+	// 已经着色，输出不需要再处理
+	e.spans.ok = false
+}
+
+// EncodeFields 实现 FieldsEncoder
+func (ColorEncoder) EncodeFields(l *Log, fields []Field) {
+	l.Fields(fields)
+}
+
+// colorizeText 将 TextEncoder 输出的一行日志 b 着色后写入 l ，
+// e.spans 是 b 中各个部分的位置
+func colorizeText(l *Log, e *Entry, b []byte) {
+	sp := &e.spans
+	// 名称，不包括后面的空格
+	if sp.name[1] > sp.name[0] {
+		l.b = append(l.b, colorBold...)
+		l.b = append(l.b, b[sp.name[0]:sp.name[1]-1]...)
+		l.b = append(l.b, colorReset...)
+		l.b = append(l.b, ' ')
+	}
+	// 级别，不包括后面的空格
+	l.b = append(l.b, levelColors[e.Level]...)
+	l.b = append(l.b, b[sp.level[0]:sp.level[1]-1]...)
+	l.b = append(l.b, colorReset...)
+	l.b = append(l.b, ' ')
+	// 头
+	if sp.header[1] > sp.header[0] {
+		l.b = append(l.b, colorDim...)
+		l.b = append(l.b, b[sp.header[0]:sp.header[1]]...)
+		l.b = append(l.b, colorReset...)
+	}
+	l.b = append(l.b, b[sp.header[1]:sp.trace[0]]...)
+	// 追踪，不包括后面的空格
+	if sp.trace[1] > sp.trace[0] {
+		l.b = append(l.b, colorTrace...)
+		l.b = append(l.b, b[sp.trace[0]:sp.trace[1]-1]...)
+		l.b = append(l.b, colorReset...)
+		l.b = append(l.b, ' ')
+	}
+	// 剩下的
+	l.b = append(l.b, b[sp.trace[1]:]...)
+}
+
+// IsTerminal 返回 w 是否是终端
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorEnabled 返回输出到 w 是否应该着色，
+// w 是终端，没有设置 NO_COLOR 环境变量，并且 TERM 不是 dumb
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(w)
+}
+
+// ConsoleEncoder 返回适合 w 的 Encoder ，ColorEnabled 返回 ColorEncoder ，否则 TextEncoder
+func ConsoleEncoder(w io.Writer) Encoder {
+	if ColorEnabled(w) {
+		return ColorEncoder{}
+	}
+	return TextEncoder{}
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_ColorEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	lg := NewLogger(&buf, func(log *Log, depth int) {
+		log.b = append(log.b, "12:00:00"...)
+	}, "app")
+	lg.Encoder = ColorEncoder{}
+	lg.ErrorTrace("t1", "hello")
+	if buf.String() != "\x1b[1m[app]\x1b[0m \x1b[31m[E]\x1b[0m \x1b[2m12:00:00\x1b[0m \x1b[1;36m[t1]\x1b[0m hello\n" {
+		t.Fatalf("%q", buf.String())
+	}
+	// 没有名称，头和追踪
+	buf.Reset()
+	lg = NewLogger(&buf, nil, "")
+	lg.Encoder = ColorEncoder{}
+	lg.Warn("hello")
+	if buf.String() != "\x1b[33m[W]\x1b[0m hello\n" {
+		t.Fatalf("%q", buf.String())
+	}
+}
+
+func Test_ColorEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	if IsTerminal(&buf) || ColorEnabled(&buf) {
+		t.FailNow()
+	}
+	if _, ok := ConsoleEncoder(&buf).(TextEncoder); !ok {
+		t.FailNow()
+	}
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(os.Stdout) {
+		t.FailNow()
+	}
+}
+
+func Test_FileColor(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFile(&FileConfig{
+		RootDir:      dir,
+		MaxFileSize:  "1M",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+		Std:          "out",
+		Color:        "always",
+		UTC:          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	f.std = &buf
+	lg := NewLogger(f, nil, "")
+	lg.Info("hello")
+	f.Close()
+	if buf.String() != "\x1b[32m[I]\x1b[0m hello\n" {
+		t.Fatalf("%q", buf.String())
+	}
+	dateDir := filepath.Join(dir, time.Now().UTC().Format(dirNameFormat))
+	entries, err := os.ReadDir(dateDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatal(err, entries)
+	}
+	d, err := os.ReadFile(filepath.Join(dateDir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(d) != "[I] hello\n" {
+		t.Fatalf("%q", d)
+	}
+}
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// 压缩中的临时文件后缀
+	compressTempExt = ".tmp"
+)
+
+var (
+	compressorLock sync.RWMutex
+	// 名称 -> 压缩
+	compressors = map[string]*compressor{
+		"gzip": {
+			ext: ".gz",
+			new: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			},
+		},
+	}
+)
+
+// Compressor 返回将数据压缩后写入 w 的 io.WriteCloser
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// compressor 是注册的压缩
+type compressor struct {
+	// 文件后缀，比如 .gz
+	ext string
+	new Compressor
+}
+
+// RegisterCompressor 注册 FileConfig.Compress 可以使用的压缩，ext 是压缩文件的后缀。
+// 默认有 gzip ，比如 zstd 可以这样注册：
+//
+//	log.RegisterCompressor("zstd", ".zst", func(w io.Writer) (io.WriteCloser, error) {
+//		return zstd.NewWriter(w)
+//	})
+func RegisterCompressor(name, ext string, c Compressor) {
+	compressorLock.Lock()
+	compressors[name] = &compressor{ext: ext, new: c}
+	compressorLock.Unlock()
+}
+
+// getCompressor 返回注册的压缩
+func getCompressor(name string) (*compressor, error) {
+	compressorLock.RLock()
+	c, ok := compressors[name]
+	compressorLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown compressor %q", name)
+	}
+	return c, nil
+}
+
+// isCompressed 返回文件名是否是压缩的文件，或者压缩中的临时文件
+func isCompressed(name string) bool {
+	if strings.HasSuffix(name, compressTempExt) {
+		return true
+	}
+	compressorLock.RLock()
+	defer compressorLock.RUnlock()
+	for _, c := range compressors {
+		if strings.HasSuffix(name, c.ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressFile 压缩 path 到 path+ext ，保留修改时间，然后删除 path
+func (c *compressor) compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	// 先写到临时文件
+	tmp := path + c.ext + compressTempExt
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = c.copy(dst, src)
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// 保留修改时间，过期检查使用
+	err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	err = os.Rename(tmp, path+c.ext)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// copy 将 src 压缩到 dst
+func (c *compressor) copy(dst io.Writer, src io.Reader) error {
+	w, err := c.new(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	if err1 := w.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
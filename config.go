@@ -0,0 +1,398 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 是 NewLoggers 的参数，可以使用 LoadConfig 从 JSON 或者 YAML 文件中读取。
+//
+// 创建之前会使用环境变量覆盖配置
+//
+//	LOG_DEFAULT 默认的 Logger
+//	LOG_LEVEL 所有 Logger 的级别
+//	LOG_<NAME>_LEVEL 名称是 name 的 Logger 的级别，名称转成大写，非字母数字转成 _
+//	LOG_HEADER 所有 Logger 的头格式
+//	LOG_ENCODER 所有 Logger 的编码
+type Config struct {
+	// 默认的 Logger 的名称，会调用 SetLogger ，空使用第一个，
+	// Loggers.Reload 的时候不能修改
+	Default string `json:"default" yaml:"default"`
+	// 所有的 Logger ，名称不能重复
+	Loggers []*LoggerConfig `json:"loggers" yaml:"loggers" validate:"required,min=1,dive"`
+}
+
+// LoggerConfig 是一个 Logger 的配置
+type LoggerConfig struct {
+	// 名称
+	Name string `json:"name" yaml:"name"`
+	// 最小级别，debug/info/warn/error/panic/fatal ，默认是 debug
+	Level string `json:"level" yaml:"level" validate:"omitempty,oneof=debug info warn error panic fatal"`
+	// 头格式，default/none/filename/filepath/funcname 或者 NewHeader 的模板，默认是 default
+	Header string `json:"header" yaml:"header"`
+	// 时间的格式，nil 使用 FormatTime ，JSON 使用 RFC3339Nano
+	Time *TimeConfig `json:"time" yaml:"time"`
+	// 编码，text/color/console/json/header ，默认是 text ，
+	// console 在所有的输出都是终端的时候使用 color ，否则使用 text ，
+	// json 在头格式是 filename/filepath/funcname 的时候输出 caller
+	Encoder string `json:"encoder" yaml:"encoder" validate:"omitempty,oneof=text color console json header"`
+	// 输出，默认是 stdout
+	Outputs []*OutputConfig `json:"outputs" yaml:"outputs" validate:"dive"`
+}
+
+// OutputConfig 是一个输出的配置
+type OutputConfig struct {
+	// 类型，stdout/stderr/file/kafka/syslog/journald/net/http/otlp
+	Type string `json:"type" yaml:"type" validate:"required,oneof=stdout stderr file kafka syslog journald net http otlp"`
+	// 类型是 file 的配置
+	File *FileConfig `json:"file" yaml:"file"`
+	// 类型是 kafka 的配置
+	Kafka *KafkaConfig `json:"kafka" yaml:"kafka"`
+	// 类型是 syslog 的配置，nil 使用本地的 syslog
+	Syslog *SyslogConfig `json:"syslog" yaml:"syslog"`
+	// 类型是 journald 的配置，nil 使用默认的
+	Journald *JournaldConfig `json:"journald" yaml:"journald"`
+	// 类型是 net 的配置
+	Net *NetConfig `json:"net" yaml:"net"`
+	// 类型是 http 的配置
+	HTTP *HTTPConfig `json:"http" yaml:"http"`
+	// 类型是 otlp 的配置，nil 使用默认的
+	OTLP *OTLPConfig `json:"otlp" yaml:"otlp"`
+	// 不为 nil 的时候使用 Async 包装
+	Async *AsyncConfig `json:"async" yaml:"async"`
+}
+
+// LoadConfig 从文件中读取 Config ，扩展名是 .yaml 和 .yml 的是 YAML ，其他的是 JSON
+func LoadConfig(path string) (*Config, error) {
+	d, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	conf := new(Config)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(d, conf)
+	default:
+		err = json.Unmarshal(d, conf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return conf, nil
+}
+
+// NewLoggers 检查配置，使用环境变量覆盖，然后创建所有的 Logger 和输出，
+// 并使用默认的 Logger 调用 SetLogger 。
+// 检查的错误是 ValidationErrors ，包含了每一个字段的错误。
+// 返回的 Loggers 可以使用 Reload 或者 Watch 重新加载配置。
+func NewLoggers(conf *Config) (*Loggers, error) {
+	return newLoggers(conf, os.LookupEnv)
+}
+
+// newLoggers 是 NewLoggers 的实现，lookupEnv 用于测试
+func newLoggers(conf *Config, lookupEnv func(string) (string, bool)) (*Loggers, error) {
+	ls := &Loggers{
+		lookupEnv: lookupEnv,
+		loggers:   make(map[string]*managedLogger),
+	}
+	err := ls.Reload(conf)
+	if err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+// withEnv 返回使用环境变量覆盖之后的配置，不修改 c
+func (c *Config) withEnv(lookupEnv func(string) (string, bool)) *Config {
+	cc := *c
+	if v, ok := lookupEnv("LOG_DEFAULT"); ok {
+		cc.Default = v
+	}
+	cc.Loggers = make([]*LoggerConfig, len(c.Loggers))
+	for i, lc := range c.Loggers {
+		if lc == nil {
+			continue
+		}
+		l := *lc
+		if v, ok := lookupEnv("LOG_LEVEL"); ok {
+			l.Level = strings.ToLower(v)
+		}
+		if v, ok := lookupEnv("LOG_" + envName(l.Name) + "LEVEL"); ok {
+			l.Level = strings.ToLower(v)
+		}
+		if v, ok := lookupEnv("LOG_HEADER"); ok {
+			l.Header = v
+		}
+		if v, ok := lookupEnv("LOG_ENCODER"); ok {
+			l.Encoder = v
+		}
+		cc.Loggers[i] = &l
+	}
+	return &cc
+}
+
+// envName 返回环境变量中的名称 "NAME_" ，空返回空
+func envName(name string) string {
+	if name == "" {
+		return ""
+	}
+	b := []byte(strings.ToUpper(name))
+	for i, c := range b {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b) + "_"
+}
+
+// newOutputs 创建所有的输出，返回 Logger 使用的输出，错误以 ".field" 开头
+func newOutputs(confs []*OutputConfig) (io.Writer, []io.Writer, error) {
+	var ws []io.Writer
+	for i, oc := range confs {
+		w, err := newOutput(oc)
+		if err != nil {
+			closeOutputs(ws)
+			return nil, nil, fmt.Errorf(".outputs[%d]: %w", i, err)
+		}
+		ws = append(ws, w)
+	}
+	switch len(ws) {
+	case 0:
+		return os.Stdout, nil, nil
+	case 1:
+		return ws[0], ws, nil
+	}
+	return MultiWriter(ws), ws, nil
+}
+
+// newLoggerState 返回配置的级别，头格式和编码，错误以 ".field" 开头
+func newLoggerState(conf *LoggerConfig) (Level, *loggerState, error) {
+	// 级别
+	level := DebugLevel
+	if conf.Level != "" {
+		var err error
+		level, err = ParseLevel(conf.Level)
+		if err != nil {
+			return level, nil, fmt.Errorf(".level: %w", err)
+		}
+	}
+	// 时间
+	var tf *TimeFormatter
+	if conf.Time != nil {
+		var err error
+		tf, err = NewTimeFormatter(conf.Time)
+		if err != nil {
+			return level, nil, fmt.Errorf(".time: %w", err)
+		}
+	}
+	// 头
+	header, err := newConfigHeader(conf.Header, tf)
+	if err != nil {
+		return level, nil, fmt.Errorf(".header: %w", err)
+	}
+	st := &loggerState{header: header, encoder: defaultEncoder}
+	// 编码
+	switch conf.Encoder {
+	case "color":
+		st.encoder = ColorEncoder{}
+	case "console":
+		if consoleColor(conf.Outputs) {
+			st.encoder = ColorEncoder{}
+		}
+	case "json":
+		enc := &JSONEncoder{Time: tf}
+		switch conf.Header {
+		case "filepath":
+			enc.FullPath = true
+			fallthrough
+		case "filename", "funcname":
+			enc.Caller = true
+		}
+		st.encoder = enc
+	case "header":
+		st.encoder = HeaderEncoder{}
+	}
+	return level, st, nil
+}
+
+// consoleColor 返回所有的输出是否都是可以着色的终端
+func consoleColor(outputs []*OutputConfig) bool {
+	if len(outputs) < 1 {
+		return ColorEnabled(os.Stdout)
+	}
+	for _, oc := range outputs {
+		switch oc.Type {
+		case "stdout":
+			if !ColorEnabled(os.Stdout) {
+				return false
+			}
+		case "stderr":
+			if !ColorEnabled(os.Stderr) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newConfigHeader 返回配置的头格式
+func newConfigHeader(name string, tf *TimeFormatter) (FormatHeader, error) {
+	switch name {
+	case "", "default":
+		if tf != nil {
+			return tf.Header, nil
+		}
+		return DefaultHeader, nil
+	case "none":
+		return nil, nil
+	case "filename":
+		if tf != nil {
+			return tf.FileNameHeader, nil
+		}
+		return FileNameHeader, nil
+	case "filepath":
+		if tf != nil {
+			return tf.FilePathHeader, nil
+		}
+		return FilePathHeader, nil
+	case "funcname":
+		if tf != nil {
+			return (&CallerFormat{Time: tf, Func: true}).Header, nil
+		}
+		return FuncNameHeader, nil
+	}
+	if !strings.Contains(name, "{") {
+		return nil, fmt.Errorf("unknown header %q", name)
+	}
+	return NewHeader(name)
+}
+
+// newOutput 创建一个输出
+func newOutput(conf *OutputConfig) (io.Writer, error) {
+	var w io.Writer
+	switch conf.Type {
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	case "file":
+		if conf.File == nil {
+			return nil, errors.New("file: config is nil")
+		}
+		f, err := NewFile(conf.File)
+		if err != nil {
+			return nil, fmt.Errorf("file: %w", err)
+		}
+		w = f
+	case "kafka":
+		if conf.Kafka == nil {
+			return nil, errors.New("kafka: config is nil")
+		}
+		k, err := NewKafka(conf.Kafka)
+		if err != nil {
+			return nil, fmt.Errorf("kafka: %w", err)
+		}
+		w = k
+	case "syslog":
+		sc := conf.Syslog
+		if sc == nil {
+			sc = new(SyslogConfig)
+		}
+		s, err := NewSyslog(sc)
+		if err != nil {
+			return nil, fmt.Errorf("syslog: %w", err)
+		}
+		w = s
+	case "journald":
+		jc := conf.Journald
+		if jc == nil {
+			jc = new(JournaldConfig)
+		}
+		j, err := NewJournald(jc)
+		if err != nil {
+			return nil, fmt.Errorf("journald: %w", err)
+		}
+		w = j
+	case "net":
+		if conf.Net == nil {
+			return nil, errors.New("net: config is nil")
+		}
+		n, err := NewNet(conf.Net)
+		if err != nil {
+			return nil, fmt.Errorf("net: %w", err)
+		}
+		w = n
+	case "http":
+		if conf.HTTP == nil {
+			return nil, errors.New("http: config is nil")
+		}
+		h, err := NewHTTP(conf.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("http: %w", err)
+		}
+		w = h
+	case "otlp":
+		oc := conf.OTLP
+		if oc == nil {
+			oc = new(OTLPConfig)
+		}
+		o, err := NewOTLP(oc)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: %w", err)
+		}
+		w = o
+	default:
+		return nil, fmt.Errorf("type: unknown %q", conf.Type)
+	}
+	if conf.Async != nil {
+		// Async 关闭的时候会关闭真正的输出
+		if w == os.Stdout || w == os.Stderr {
+			w = struct{ io.Writer }{w}
+		}
+		a, err := NewAsync(w, conf.Async)
+		if err != nil {
+			closeOutput(w)
+			return nil, fmt.Errorf("async: %w", err)
+		}
+		w = a
+	}
+	// Fatal 退出之前同步，File 在 NewFile 中已经注册
+	if f, ok := w.(Flusher); ok {
+		if _, ok := w.(*File); !ok {
+			RegisterFlusher(f)
+		}
+	}
+	return w, nil
+}
+
+// closeOutput 关闭输出，不关闭 os.Stdout 和 os.Stderr
+func closeOutput(w io.Writer) error {
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	if f, ok := w.(Flusher); ok {
+		UnregisterFlusher(f)
+	}
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// closeOutputs 关闭所有的输出
+func closeOutputs(ws []io.Writer) error {
+	var errs []error
+	for _, w := range ws {
+		errs = append(errs, closeOutput(w))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,201 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_NewLoggers(t *testing.T) {
+	defaultLogger := DefaultLogger
+	defer SetLogger(defaultLogger)
+	dir, dbDir := t.TempDir(), t.TempDir()
+	conf := &Config{
+		Default: "app",
+		Loggers: []*LoggerConfig{
+			{
+				Name:    "db",
+				Level:   "warn",
+				Encoder: "json",
+				Outputs: []*OutputConfig{
+					{Type: "file", File: &FileConfig{RootDir: dbDir, MaxFileSize: "1M", MaxKeepDay: 1, SyncInterval: 10}},
+				},
+			},
+			{
+				Name:   "app",
+				Level:  "info",
+				Header: "{level:short} {name}",
+				Outputs: []*OutputConfig{
+					{Type: "file", File: &FileConfig{RootDir: dir, MaxFileSize: "1M", MaxKeepDay: 1, SyncInterval: 10}},
+					{Type: "stderr", Async: &AsyncConfig{}},
+				},
+				Encoder: "header",
+			},
+		},
+	}
+	env := map[string]string{"LOG_DB_LEVEL": "ERROR"}
+	ls, err := newLoggers(conf, func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if DefaultLogger != ls.Logger("app") || ls.Default() != ls.Logger("app") {
+		t.FailNow()
+	}
+	// 环境变量覆盖，不修改配置
+	if ls.Logger("db").Level() != ErrorLevel || conf.Loggers[0].Level != "warn" {
+		t.FailNow()
+	}
+	db := ls.Logger("db")
+	db.Warn("warn")
+	db.Error("error")
+	lg := ls.Logger("app")
+	if lg.Level() != InfoLevel {
+		t.FailNow()
+	}
+	lg.Debug("debug")
+	lg.Info("hello")
+	if err := ls.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 文件
+	if s := readDir(t, dir); s != "I app hello\n" {
+		t.Fatalf("%q", s)
+	}
+	// json
+	var m map[string]any
+	s := readDir(t, dbDir)
+	if err := json.Unmarshal([]byte(s), &m); err != nil || m["level"] != "error" || m["logger"] != "db" || m["msg"] != "error" {
+		t.Fatalf("%q", s)
+	}
+}
+
+func Test_NewLoggersError(t *testing.T) {
+	defaultLogger := DefaultLogger
+	_, err := NewLoggers(&Config{
+		Loggers: []*LoggerConfig{
+			{
+				Level:   "trace",
+				Encoder: "xml",
+				Outputs: []*OutputConfig{
+					{Type: "file", File: &FileConfig{MaxKeepDay: 1, SyncInterval: 1}},
+					{},
+				},
+			},
+		},
+	})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 5 {
+		t.Fatal(err)
+	}
+	for i, s := range []string{
+		"loggers[0].level",
+		"loggers[0].encoder",
+		"loggers[0].outputs[0].file.rootDir",
+		"loggers[0].outputs[0].file.syncInterval",
+		"loggers[0].outputs[1].type",
+	} {
+		if errs[i].Field != s {
+			t.Fatal(errs[i])
+		}
+	}
+	// nil 的元素
+	var conf Config
+	err = json.Unmarshal([]byte(`{"loggers":[null,{"outputs":[null]}]}`), &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewLoggers(&conf)
+	if !errors.As(err, &errs) || len(errs) != 2 ||
+		errs[0].Field != "loggers[0]" || errs[0].Rule != "required" ||
+		errs[1].Field != "loggers[1].outputs[0]" || errs[1].Rule != "required" {
+		t.Fatal(err)
+	}
+	// 创建的错误
+	_, err = NewLoggers(&Config{Loggers: []*LoggerConfig{{Header: "{x}"}}})
+	if err == nil || !strings.HasPrefix(err.Error(), "loggers[0].header: ") {
+		t.Fatal(err)
+	}
+	_, err = NewLoggers(&Config{Default: "x", Loggers: []*LoggerConfig{{}}})
+	if err == nil {
+		t.FailNow()
+	}
+	if DefaultLogger != defaultLogger {
+		t.FailNow()
+	}
+}
+
+func Test_ValidateOmitempty(t *testing.T) {
+	// omitempty 前面的规则检查空值，后面的只检查不为空的值
+	for _, c := range []struct {
+		conf *FileConfig
+		rule string
+	}{
+		{&FileConfig{Path: "a.log", SyncInterval: 10}, ""},
+		{&FileConfig{SyncInterval: 10, MaxKeepDay: 1}, "required_without=Path"},
+		{&FileConfig{RootDir: "logs", SyncInterval: 10}, "required_without=Path"},
+		{&FileConfig{RootDir: "logs", SyncInterval: 10, MaxKeepDay: -1}, "min=1"},
+	} {
+		err := validateConfig(c.conf)
+		var errs ValidationErrors
+		if c.rule == "" {
+			if err != nil {
+				t.Fatal(err)
+			}
+		} else if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Rule != c.rule {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_LoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "log.yaml")
+	err := os.WriteFile(yamlPath, []byte(`default: app
+loggers:
+  - name: app
+    level: warn
+    encoder: json
+    outputs:
+      - type: file
+        file:
+          rootDir: /var/log/app
+          maxFileSize: 10M
+          maxKeepDay: 7
+          syncInterval: 1000
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "log.json")
+	err = os.WriteFile(jsonPath, []byte(`{"default":"app","loggers":[{"name":"app","level":"warn","encoder":"json",`+
+		`"outputs":[{"type":"file","file":{"rootDir":"/var/log/app","maxFileSize":"10M","maxKeepDay":7,"syncInterval":1000}}]}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := LoadConfig(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := LoadConfig(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(y, j) || y.Loggers[0].Outputs[0].File.MaxKeepDay != 7 {
+		t.Fatal(y)
+	}
+	// 错误的格式
+	err = os.WriteFile(yamlPath, []byte("loggers: {"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadConfig(yamlPath); err == nil {
+		t.FailNow()
+	}
+}
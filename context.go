@@ -0,0 +1,72 @@
+package log
+
+import "context"
+
+// context 的键
+type contextKey int
+
+const (
+	traceContextKey contextKey = iota
+	spanContextKey
+	fieldsContextKey
+)
+
+const (
+	// span 字段的键
+	spanFieldKey = "span"
+)
+
+// ContextExtractor 从 context 中提取追踪和字段，用于 Logger 的 XxxCtx 函数
+type ContextExtractor func(ctx context.Context) (trace string, fields []Field)
+
+// ContextWithTrace 返回保存了 traceID 的 context
+func ContextWithTrace(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceContextKey, traceID)
+}
+
+// ContextWithSpan 返回保存了 spanID 的 context
+func ContextWithSpan(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, spanContextKey, spanID)
+}
+
+// ContextWithFields 返回保存了字段的 context ，会添加到 ctx 中已有的字段后面
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	old := FieldsFromContext(ctx)
+	all := make([]Field, 0, len(old)+len(fields))
+	all = append(all, old...)
+	all = append(all, fields...)
+	return context.WithValue(ctx, fieldsContextKey, all)
+}
+
+// TraceFromContext 返回 ContextWithTrace 保存的 traceID
+func TraceFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(traceContextKey).(string)
+	return s
+}
+
+// SpanFromContext 返回 ContextWithSpan 保存的 spanID
+func SpanFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(spanContextKey).(string)
+	return s
+}
+
+// FieldsFromContext 返回 ContextWithFields 保存的字段
+func FieldsFromContext(ctx context.Context) []Field {
+	fs, _ := ctx.Value(fieldsContextKey).([]Field)
+	return fs
+}
+
+// DefaultContextExtractor 返回 ContextWithTrace 保存的追踪，
+// ContextWithSpan 保存的 span 字段和 ContextWithFields 保存的字段
+func DefaultContextExtractor(ctx context.Context) (string, []Field) {
+	trace := TraceFromContext(ctx)
+	fields := FieldsFromContext(ctx)
+	span := SpanFromContext(ctx)
+	if span == "" {
+		return trace, fields
+	}
+	all := make([]Field, 0, len(fields)+1)
+	all = append(all, String(spanFieldKey, span))
+	all = append(all, fields...)
+	return trace, all
+}
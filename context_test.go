@@ -0,0 +1,54 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_LoggerCtx(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "")
+	ctx := ContextWithTrace(context.Background(), "t1")
+	ctx = ContextWithSpan(ctx, "s1")
+	ctx = ContextWithFields(ctx, String("user", "u1"))
+	ctx = ContextWithFields(ctx, Int("n", 1))
+	lg.InfoCtx(ctx, "hello")
+	if !strings.HasSuffix(buf.String(), " [t1] hello span=s1 user=u1 n=1\n") {
+		t.Fatal(buf.String())
+	}
+	buf.Reset()
+	lg.ErrorFieldsCtx(ctx, "world", Bool("ok", true))
+	if !strings.HasSuffix(buf.String(), " [t1] world span=s1 user=u1 n=1 ok=true\n") {
+		t.Fatal(buf.String())
+	}
+	// 没有数据
+	buf.Reset()
+	lg.WarnfCtx(context.Background(), "%d", 1)
+	if !strings.HasPrefix(buf.String(), "[W] ") || strings.Contains(buf.String(), "[t1]") {
+		t.Fatal(buf.String())
+	}
+	// 自定义
+	buf.Reset()
+	type requestID struct{}
+	lg.Extractor = func(ctx context.Context) (string, []Field) {
+		id, _ := ctx.Value(requestID{}).(string)
+		return id, nil
+	}
+	lg.DebugCtx(context.WithValue(ctx, requestID{}, "r1"), "custom")
+	if !strings.HasSuffix(buf.String(), " [r1] custom\n") {
+		t.Fatal(buf.String())
+	}
+}
+
+func Test_SlogHandlerCtx(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "")
+	sl := slog.New(NewSlogHandler(lg, ""))
+	ctx := ContextWithTrace(context.Background(), "t1")
+	sl.InfoContext(ctx, "hello", "a", 1)
+	if !strings.HasSuffix(buf.String(), " [t1] hello a=1\n") {
+		t.Fatal(buf.String())
+	}
+}
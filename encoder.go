@@ -0,0 +1,151 @@
+package log
+
+import "runtime"
+
+// Entry 是一行日志的内容，由 Encoder 编码
+type Entry struct {
+	// 在 Encoder.Encode 中调用 runtime.Caller 的深度
+	Depth int
+	// 调用者的 pc ，不为 0 时代替 Depth
+	PC uintptr
+	// 级别
+	Level Level
+	// 名称
+	Name string
+	// 追踪
+	Trace string
+	// 头格式，TextEncoder 使用
+	Header FormatHeader
+	// 日志内容
+	Message []byte
+	// Logger.With 绑定的字段
+	Bound []Field
+	// 当前 Encoder 预先编码的 Bound ，不为 nil 时直接写入
+	BoundEncoded []byte
+	// 字段
+	Fields []Field
+	// TextEncoder 输出的各个部分的位置，用于着色
+	spans textSpans
+}
+
+// reset 清理引用，放回缓存池
+func (e *Entry) reset() {
+	e.Header = nil
+	e.Message = nil
+	e.Bound = nil
+	e.BoundEncoded = nil
+	e.Fields = nil
+	e.spans = textSpans{}
+}
+
+// Caller 返回调用日志函数的文件和行号，
+// 只能在 Encoder.Encode 或者 EntryWriter.WriteEntry 中直接调用
+func (e *Entry) Caller() (path string, line int, ok bool) {
+	if e.PC != 0 {
+		return pcCaller(e.PC)
+	}
+	_, path, line, ok = runtime.Caller(e.Depth + 1)
+	return
+}
+
+// CallerPC 返回调用日志函数的 pc ，用于在其他协程中得到调用者，
+// 只能在 Encoder.Encode 或者 EntryWriter.WriteEntry 中直接调用
+func (e *Entry) CallerPC() uintptr {
+	if e.PC != 0 {
+		return e.PC
+	}
+	var pcs [1]uintptr
+	runtime.Callers(e.Depth+2, pcs[:])
+	return pcs[0]
+}
+
+// EntryWriter 是可以得到 Entry 的输出，Logger 会调用 WriteEntry 代替 Write 。
+// b 是编码后的一行日志，返回后会被回收，需要保存的话要复制。
+type EntryWriter interface {
+	WriteEntry(e *Entry, b []byte) (int, error)
+}
+
+// Encoder 用于将 Entry 编码到 Log ，需要包含换行
+type Encoder interface {
+	Encode(l *Log, e *Entry)
+}
+
+// FieldsEncoder 是可以预先编码字段的 Encoder ，
+// Logger.With 使用它编码一次绑定的字段，输出的时候设置到 Entry.BoundEncoded
+type FieldsEncoder interface {
+	Encoder
+	EncodeFields(l *Log, fields []Field)
+}
+
+// TextEncoder 输出 "[name] [level] Header [traceID] text k=v"
+type TextEncoder struct{}
+
+// Encode 实现 Encoder
+func (TextEncoder) Encode(l *Log, e *Entry) {
+	appendText(l, e, e.Depth+1)
+}
+
+// EncodeFields 实现 FieldsEncoder
+func (TextEncoder) EncodeFields(l *Log, fields []Field) {
+	l.Fields(fields)
+}
+
+// textSpans 是 TextEncoder 输出的各个部分在一行日志中的位置
+type textSpans struct {
+	// 是否是 TextEncoder 输出的
+	ok     bool
+	name   [2]int
+	level  [2]int
+	header [2]int
+	trace  [2]int
+}
+
+// appendText 以 TextEncoder 的格式写入，并记录各个部分的位置，
+// depth 是 runtime.Caller 在 appendText 中的深度
+func appendText(l *Log, e *Entry, depth int) {
+	start := len(l.b)
+	sp := &e.spans
+	sp.ok = true
+	// 名称
+	sp.name[0] = len(l.b) - start
+	if e.Name != "" {
+		l.b = append(l.b, '[')
+		l.b = append(l.b, e.Name...)
+		l.b = append(l.b, ']')
+		l.b = append(l.b, ' ')
+	}
+	sp.name[1] = len(l.b) - start
+	// 级别
+	sp.level[0] = sp.name[1]
+	l.b = append(l.b, levels[e.Level]...)
+	sp.level[1] = len(l.b) - start
+	// 头
+	sp.header[0] = sp.level[1]
+	if e.Header != nil {
+		e.Header(l, depth+1)
+		sp.header[1] = len(l.b) - start
+		l.b = append(l.b, ' ')
+	} else {
+		sp.header[1] = sp.header[0]
+	}
+	// 追踪
+	sp.trace[0] = len(l.b) - start
+	if e.Trace != "" {
+		l.b = append(l.b, '[')
+		l.b = append(l.b, e.Trace...)
+		l.b = append(l.b, ']')
+		l.b = append(l.b, ' ')
+	}
+	sp.trace[1] = len(l.b) - start
+	// 日志
+	l.b = append(l.b, e.Message...)
+	// 字段
+	if e.BoundEncoded != nil {
+		l.b = append(l.b, e.BoundEncoded...)
+	} else {
+		l.Fields(e.Bound)
+	}
+	l.Fields(e.Fields)
+	// 换行
+	l.b = append(l.b, '\n')
+}
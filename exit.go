@@ -0,0 +1,61 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	// Exit 是 Fatal 输出日志之后调用的退出函数，测试的时候可以替换
+	Exit = os.Exit
+	// 注册的 Flusher
+	flushers     []Flusher
+	flushersLock sync.Mutex
+)
+
+// RegisterFlusher 注册 Fatal 退出之前需要同步的输出，
+// 输出关闭之后需要调用 UnregisterFlusher 。
+// NewFile 和 NewLoggers 会注册它们创建的输出，File 的 Close 会自动删除。
+func RegisterFlusher(f Flusher) {
+	flushersLock.Lock()
+	flushers = append(flushers, f)
+	flushersLock.Unlock()
+}
+
+// UnregisterFlusher 删除 RegisterFlusher 注册的 f ，f 需要是可以比较的类型，比如指针
+func UnregisterFlusher(f Flusher) {
+	flushersLock.Lock()
+	defer flushersLock.Unlock()
+	for i := range flushers {
+		if flushers[i] == f {
+			flushers = append(flushers[:i], flushers[i+1:]...)
+			return
+		}
+	}
+}
+
+// FlushAll 同步所有注册的输出
+func FlushAll() {
+	flushersLock.Lock()
+	fs := append([]Flusher(nil), flushers...)
+	flushersLock.Unlock()
+	for _, f := range fs {
+		err := f.Flush()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// exit 同步 Logger 的输出和注册的输出，然后调用 Exit(1)
+func (lg *Logger) exit() {
+	if f, ok := lg.Writer.(Flusher); ok {
+		err := f.Flush()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	FlushAll()
+	Exit(1)
+}
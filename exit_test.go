@@ -0,0 +1,66 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Fatal(t *testing.T) {
+	code := 0
+	Exit = func(c int) { code = c }
+	defer func() { Exit = os.Exit }()
+	w := new(testEntryWriter)
+	other := new(testEntryWriter)
+	RegisterFlusher(other)
+	t.Cleanup(func() { UnregisterFlusher(other) })
+	lg := NewLogger(w, DefaultHeader, "")
+	lg.FatalfTrace("t", "%d", 1)
+	if code != 1 || w.flushed != 1 || other.flushed != 1 {
+		t.FailNow()
+	}
+	if !strings.HasPrefix(w.lines[0], "[F] ") || !strings.HasSuffix(w.lines[0], " [t] 1\n") {
+		t.Fatal(w.lines[0])
+	}
+	// 删除之后不再同步
+	UnregisterFlusher(other)
+	FlushAll()
+	if other.flushed != 1 {
+		t.FailNow()
+	}
+	// 直接创建的 File 也会同步
+	dir := t.TempDir()
+	f, err := NewFile(&FileConfig{RootDir: dir, MaxFileSize: "1M", MaxKeepDay: 1, SyncInterval: 1000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Write([]byte("file\n"))
+	lg.Fatal("exit")
+	if s := readDir(t, dir); s != "file\n" {
+		t.Fatalf("%q", s)
+	}
+	// 关闭之后删除
+	f.Close()
+	flushersLock.Lock()
+	defer flushersLock.Unlock()
+	for _, fl := range flushers {
+		if fl == Flusher(f) {
+			t.FailNow()
+		}
+	}
+}
+
+func Test_Panic(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "")
+	defer func() {
+		if recover() != "1 2" {
+			t.FailNow()
+		}
+		if !strings.HasPrefix(buf.String(), "[P] ") || !strings.HasSuffix(buf.String(), " 1 2\n") {
+			t.Fatal(buf.String())
+		}
+	}()
+	lg.Panic(1, " 2")
+}
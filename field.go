@@ -0,0 +1,212 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// FieldType 是 Field 值的类型
+type FieldType uint8
+
+// 字段类型
+const (
+	// AnyType 任意值，使用 fmt 格式化
+	AnyType FieldType = iota
+	// StringType 字符串，值在 Str
+	StringType
+	// IntType 有符号整数，值在 Int
+	IntType
+	// UintType 无符号整数，值在 Int
+	UintType
+	// FloatType 浮点数，值是 Int 的 math.Float64bits
+	FloatType
+	// BoolType 布尔，值在 Int ，1 是 true
+	BoolType
+	// DurationType 时长，值在 Int
+	DurationType
+	// TimeType 时间，值是 Int 的 UnixNano ，Any 是 *time.Location
+	TimeType
+	// ErrorType 错误，值在 Any
+	ErrorType
+)
+
+const (
+	// 时间字段的格式
+	fieldTimeLayout = time.RFC3339Nano
+)
+
+// Field 是一个结构化的键值对，使用 String/Int/Bool... 这些函数创建。
+// 常用类型的值直接保存在字段中，输出的时候不需要经过 fmt 。
+type Field struct {
+	// 键
+	Key string
+	// 值的类型
+	Type FieldType
+	// 整数，浮点数，布尔，时长，时间的值
+	Int int64
+	// 字符串的值
+	Str string
+	// 其他的值
+	Any any
+}
+
+// String 返回字符串字段
+func String(key, value string) Field {
+	return Field{Key: key, Type: StringType, Str: value}
+}
+
+// Int 返回整数字段
+func Int(key string, value int) Field {
+	return Field{Key: key, Type: IntType, Int: int64(value)}
+}
+
+// Int64 返回整数字段
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: IntType, Int: value}
+}
+
+// Uint64 返回无符号整数字段
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Type: UintType, Int: int64(value)}
+}
+
+// Float64 返回浮点数字段
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Type: FloatType, Int: int64(math.Float64bits(value))}
+}
+
+// Bool 返回布尔字段
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, Type: BoolType}
+	if value {
+		f.Int = 1
+	}
+	return f
+}
+
+// Duration 返回时长字段
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Int: int64(value)}
+}
+
+// Time 返回时间字段
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Type: TimeType, Int: value.UnixNano(), Any: value.Location()}
+}
+
+// Err 返回键为 "error" 的错误字段
+func Err(err error) Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr 返回错误字段
+func NamedErr(key string, err error) Field {
+	return Field{Key: key, Type: ErrorType, Any: err}
+}
+
+// Any 返回任意值的字段，常用类型会转换成对应的字段
+func Any(key string, value any) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case int32:
+		return Int64(key, int64(v))
+	case uint:
+		return Uint64(key, uint64(v))
+	case uint64:
+		return Uint64(key, v)
+	case uint32:
+		return Uint64(key, uint64(v))
+	case float64:
+		return Float64(key, v)
+	case float32:
+		return Float64(key, float64(v))
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return NamedErr(key, v)
+	}
+	return Field{Key: key, Type: AnyType, Any: value}
+}
+
+// Value 返回字段的值
+func (f *Field) Value() any {
+	switch f.Type {
+	case StringType:
+		return f.Str
+	case IntType:
+		return f.Int
+	case UintType:
+		return uint64(f.Int)
+	case FloatType:
+		return math.Float64frombits(uint64(f.Int))
+	case BoolType:
+		return f.Int == 1
+	case DurationType:
+		return time.Duration(f.Int)
+	case TimeType:
+		return f.TimeValue()
+	}
+	return f.Any
+}
+
+// TimeValue 返回 TimeType 字段的时间
+func (f *Field) TimeValue() time.Time {
+	t := time.Unix(0, f.Int)
+	if loc, ok := f.Any.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// Field 以 key=value 的格式写入字段
+func (l *Log) Field(f *Field) {
+	l.Quote(f.Key)
+	l.b = append(l.b, '=')
+	l.FieldValue(f)
+}
+
+// FieldValue 以文本格式写入字段的值
+func (l *Log) FieldValue(f *Field) {
+	switch f.Type {
+	case StringType:
+		l.Quote(f.Str)
+	case IntType:
+		l.Int64(f.Int)
+	case UintType:
+		l.Uint64(uint64(f.Int))
+	case FloatType:
+		l.Float64(math.Float64frombits(uint64(f.Int)))
+	case BoolType:
+		l.Bool(f.Int == 1)
+	case DurationType:
+		l.Duration(time.Duration(f.Int))
+	case TimeType:
+		l.Time(f.TimeValue(), fieldTimeLayout)
+	case ErrorType:
+		if f.Any == nil {
+			l.b = append(l.b, "<nil>"...)
+			return
+		}
+		l.Quote(f.Any.(error).Error())
+	default:
+		l.Quote(fmt.Sprint(f.Any))
+	}
+}
+
+// Fields 写入多个字段，每个字段前面有一个空格
+func (l *Log) Fields(fields []Field) {
+	for i := 0; i < len(fields); i++ {
+		l.b = append(l.b, ' ')
+		l.Field(&fields[i])
+	}
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Field(t *testing.T) {
+	l := &Log{}
+	for _, c := range []struct {
+		f Field
+		s string
+	}{
+		{String("s", "abc"), "s=abc"},
+		{String("s", "a b"), `s="a b"`},
+		{String("s", ""), `s=""`},
+		{Int("i", -12), "i=-12"},
+		{Uint64("u", 12), "u=12"},
+		{Float64("f", 1.5), "f=1.5"},
+		{Bool("b", true), "b=true"},
+		{Duration("d", time.Second), "d=1s"},
+		{Time("t", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)), "t=2023-01-02T03:04:05Z"},
+		{Err(errors.New("bad")), "error=bad"},
+		{Any("a", []int{1, 2}), `a="[1 2]"`},
+		{Any("a", 3), "a=3"},
+	} {
+		l.Reset()
+		l.Field(&c.f)
+		if string(l.b) != c.s {
+			t.Fatalf("%s != %s", l.b, c.s)
+		}
+	}
+}
+
+func Test_LoggerFields(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "fields")
+	lg.InfoFields("hello", String("k", "v"), Int("n", 1))
+	if !strings.HasSuffix(buf.String(), " hello k=v n=1\n") {
+		t.Fatal(buf.String())
+	}
+	buf.Reset()
+	lg.ErrorFieldsTrace("trace", "hello", Bool("ok", false))
+	if !strings.HasSuffix(buf.String(), " [trace] hello ok=false\n") {
+		t.Fatal(buf.String())
+	}
+	buf.Reset()
+	lg.DisableWarn = true
+	lg.WarnFields("hello")
+	if buf.Len() != 0 {
+		t.FailNow()
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -29,17 +31,45 @@ var (
 
 // FileConfig 是 NewFile 的参数。
 type FileConfig struct {
-	// 日志保存的根目录
-	RootDir string `json:"rootDir" yaml:"rootDir" validate:"required,dirpath"`
+	// 日志保存的根目录，没有设置 Path 的时候是必须的
+	RootDir string `json:"rootDir" yaml:"rootDir" validate:"required_without=Path,omitempty,dirpath"`
+	// 固定的日志文件路径，设置了之后代替 RootDir ，由外部的 logrotate 等工具切割，
+	// 不换新文件，不删除，不压缩，MaxFileSize 是内存数据的最大字节。
+	// 文件被移动或者删除之后，会自动重新打开
+	Path string `json:"path" yaml:"path" validate:"omitempty,filepath"`
+	// Path 模式下，收到 SIGHUP 的时候是否重新打开文件
+	ReopenOnSignal bool `json:"reopenOnSignal" yaml:"reopenOnSignal"`
 	// 每一份日志文件的最大字节，使用 1.5/K/M/G/T 这样的字符表示
 	MaxFileSize string `json:"maxFileSize" yaml:"maxFileSize"`
+	// 文件名的格式，支持 {date} {time} {time:layout} {seq} {app} {host} {pid} ，
+	// 比如 "{app}-{date}-{seq}.log" ，默认是 "{time}" ，也就是 20060102150405.000000 。
+	// 按大小或者时间换新文件的时候，需要有 {time} 或者 {seq} ，否则还是写入同一个文件
+	FileName string `json:"fileName" yaml:"fileName"`
+	// 目录结构，date 是 root/date/fileName ，flat 是 root/fileName ，默认是 date
+	Layout string `json:"layout" yaml:"layout" validate:"omitempty,oneof=date flat"`
 	// 保存的最大天数，最小是1天
-	MaxKeepDay int `json:"maxKeepDay" yaml:"maxKeepDay" validate:"required,min=1"`
+	MaxKeepDay int `json:"maxKeepDay" yaml:"maxKeepDay" validate:"required_without=Path,omitempty,min=1"`
 	// 同步到磁盘的时间间隔，单位毫秒，最小是 10
 	// 但是如果文件大小达到 MaxFileSize ，那么立即同步
 	SyncInterval int `json:"syncInterval" yaml:"syncInterval" validate:"required,min=10"`
 	// 是否输出到控制台，out/err
 	Std string `json:"std" yaml:"std" validate:"omitempty,oneof=out err"`
+	// 控制台输出是否着色，auto 是终端并且没有设置 NO_COLOR 时着色，
+	// always 总是着色，never 不着色，默认是 never ，文件中的总是不着色
+	Color string `json:"color" yaml:"color" validate:"omitempty,oneof=auto always never"`
+	// 按时间换新文件，hourly/daily ，默认是 daily
+	// 不管怎么设置，过了零点都会换新文件，保存到新日期的目录
+	Rotate string `json:"rotate" yaml:"rotate" validate:"omitempty,oneof=hourly daily"`
+	// 自定义换新文件的时间间隔，单位分钟，从零点开始计算，大于 0 时代替 Rotate
+	RotateInterval int `json:"rotateInterval" yaml:"rotateInterval" validate:"omitempty,min=1"`
+	// 目录，文件名和换新文件的时间是否使用 UTC ，默认是本地时间
+	UTC bool `json:"utc" yaml:"utc"`
+	// 换新文件之后，在后台压缩旧的文件，gzip 或者 RegisterCompressor 注册的名称，空不压缩
+	Compress string `json:"compress" yaml:"compress"`
+	// 所有日志文件的最大字节，使用 1.5/K/M/G/T 这样的字符表示，超过了从最旧的开始删除，空不限制
+	MaxTotalSize string `json:"maxTotalSize" yaml:"maxTotalSize"`
+	// 日志文件的最大数量，超过了从最旧的开始删除，0 不限制
+	MaxFiles int `json:"maxFiles" yaml:"maxFiles" validate:"omitempty,min=1"`
 }
 
 // NewFile 返回一个 File 实例。
@@ -62,31 +92,95 @@ func NewFile(conf *FileConfig) (*File, error) {
 	if syncDur < minSyncDur {
 		syncDur = minSyncDur
 	}
+	// 压缩
+	var comp *compressor
+	if conf.Compress != "" {
+		comp, err = getCompressor(conf.Compress)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// 总大小
+	var totalSize int64
+	if conf.MaxTotalSize != "" {
+		totalSize, err = ParseSize(conf.MaxTotalSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// 文件名
+	namer, err := newFileNamer(conf.FileName)
+	if err != nil {
+		return nil, err
+	}
 	// 实例
 	f := new(File)
+	f.namer = namer
+	f.flat = conf.Layout == "flat"
+	f.compressor = comp
+	f.maxTotalSize = totalSize
+	f.maxFiles = conf.MaxFiles
 	f.rootDir = conf.RootDir
 	f.maxFileSize = int(size)
 	f.exit = make(chan struct{})
 	f.maxKeepDuraion = keepDuraion
+	// 换新文件的间隔
+	f.rotateDur = 24 * time.Hour
+	if conf.RotateInterval > 0 {
+		f.rotateDur = time.Duration(conf.RotateInterval) * time.Minute
+	} else if conf.Rotate == "hourly" {
+		f.rotateDur = time.Hour
+	}
+	f.utc = conf.UTC
 	switch conf.Std {
 	case "err":
 		f.std = os.Stderr
 	case "out":
 		f.std = os.Stdout
 	}
-	// 先打开文件准备
-	f.openLast()
+	switch conf.Color {
+	case "auto":
+		f.stdColor = f.std != nil && ColorEnabled(f.std)
+	case "always":
+		f.stdColor = f.std != nil
+	}
+	// 固定路径
+	if conf.Path != "" {
+		f.path = conf.Path
+		f.openPath()
+		if conf.ReopenOnSignal {
+			f.reopenSignal = make(chan os.Signal, 1)
+			signal.Notify(f.reopenSignal, syscall.SIGHUP)
+		}
+	} else {
+		// 先打开文件准备
+		f.openLast()
+	}
 	// 启动同步协程
 	f.wait.Add(1)
 	go f.syncLoop(syncDur)
+	// 启动压缩和检查数量的协程
+	if f.path == "" && (f.compressor != nil || f.maxTotalSize > 0 || f.maxFiles > 0) {
+		f.rotateSignal = make(chan struct{}, 1)
+		f.wait.Add(1)
+		go f.rotateLoop()
+		f.signalRotate()
+	}
+	// Fatal 退出之前同步，Close 的时候删除
+	RegisterFlusher(f)
 	return f, nil
 }
 
 // File 实现了 io.Writer 接口，可以作为 Logger 的输出。
 // File 首先会将 log 保存在内存中，后台启动一个同步协程，每隔一段时间将数据同步到磁盘。
 // 如果内存的数据到了最大，会立即同步。
+// 文件大小达到最大或者到了换新文件的时间，会换新文件输出。
 // 在同步的同时，File 还会自动删除磁盘上时间超过指定天数的文件。
-// 目录结构是，root/date/time.ms
+// 如果设置了总大小或者数量，每次换新文件之后，从最旧的文件开始删除。
+// 如果设置了压缩，换新文件之后，旧的文件在压缩协程中压缩成 time.ms.gz 这样的文件。
+// 目录结构默认是，root/date/time.ms ，可以设置文件名的格式和不使用日期目录。
+// 设置了 Path 的时候，只写入这个文件，调用 Reopen 或者收到 SIGHUP 的时候重新打开，
+// 同步的时候发现文件被移动或者删除了，也会重新打开。
 type File struct {
 	lock sync.Mutex
 	wait sync.WaitGroup
@@ -108,31 +202,163 @@ type File struct {
 	maxFileSize int
 	// 控制台输出
 	std io.Writer
+	// 控制台输出是否着色
+	stdColor bool
+	// 换新文件的时间间隔
+	rotateDur time.Duration
+	// 下一次换新文件的时间
+	nextRotate time.Time
+	// 是否使用 UTC
+	utc bool
+	// 压缩，nil 不压缩
+	compressor *compressor
+	// 等待压缩的文件
+	compressQueue []string
+	// 所有日志文件的最大字节
+	maxTotalSize int64
+	// 日志文件的最大数量
+	maxFiles int
+	// 通知换新文件之后的协程
+	rotateSignal chan struct{}
+	// 文件名的格式
+	namer *fileNamer
+	// 是否不使用日期目录
+	flat bool
+	// 固定的文件路径
+	path string
+	// 重新打开的信号
+	reopenSignal chan os.Signal
 }
 
 // Write 是 io.Writer 接口。
 func (f *File) Write(b []byte) (int, error) {
+	if err := f.write(b); err != nil {
+		return 0, err
+	}
+	if f.std != nil {
+		f.std.Write(b)
+	}
+	return len(b), nil
+}
+
+// WriteEntry 实现 EntryWriter ，控制台输出着色的时候，
+// 使用 e 对 TextEncoder 的输出着色，文件中的保持不变。
+func (f *File) WriteEntry(e *Entry, b []byte) (int, error) {
+	if err := f.write(b); err != nil {
+		return 0, err
+	}
+	if f.std != nil {
+		if f.stdColor && e.spans.ok {
+			l := logPool.Get().(*Log)
+			l.b = l.b[:0]
+			colorizeText(l, e, b)
+			f.std.Write(l.b)
+			logPool.Put(l)
+		} else {
+			f.std.Write(b)
+		}
+	}
+	return len(b), nil
+}
+
+// write 写入文件
+func (f *File) write(b []byte) error {
 	f.lock.Lock()
 	// 关闭了
 	if f.closed {
 		f.lock.Unlock()
-		return 0, errFileClosed
+		return errFileClosed
+	}
+	// 到了时间，换新文件输出
+	if f.path == "" && !f.now().Before(f.nextRotate) {
+		f.rotate()
 	}
 	// 添加到内存
 	f.data = append(f.data, b...)
 	f.curFileSize += len(b)
 	// 如果内存数据达到最大了，换新文件输出
 	if f.curFileSize >= f.maxFileSize {
-		f.curFileSize = 0
-		f.flush()
-		f.close()
-		f.open()
+		f.rotate()
 	}
 	f.lock.Unlock()
-	if f.std != nil {
-		f.std.Write(b)
+	return nil
+}
+
+// rotate 同步数据，关闭当前的文件，打开新的文件，旧的文件放到压缩队列，
+// 固定路径的只同步数据
+func (f *File) rotate() {
+	f.curFileSize = 0
+	f.flush()
+	if f.path != "" {
+		return
+	}
+	name := ""
+	if f.file != nil {
+		name = f.file.Name()
+	}
+	f.close()
+	f.open()
+	if f.compressor != nil && name != "" {
+		f.compressQueue = append(f.compressQueue, name)
+	}
+	f.signalRotate()
+}
+
+// signalRotate 通知换新文件之后的协程
+func (f *File) signalRotate() {
+	if f.rotateSignal == nil {
+		return
+	}
+	select {
+	case f.rotateSignal <- struct{}{}:
+	default:
 	}
-	return len(b), nil
+}
+
+// rotateLoop 运行在一个协程中，换新文件之后，压缩队列中的文件，然后检查总大小和数量。
+// 退出前压缩完所有的文件，再检查一次。
+func (f *File) rotateLoop() {
+	defer f.wait.Done()
+	for {
+		select {
+		case <-f.rotateSignal:
+			if f.compressor != nil {
+				f.compressFiles()
+			}
+			f.checkLimit()
+		case <-f.exit:
+			if f.compressor != nil {
+				f.compressFiles()
+			}
+			f.checkLimit()
+			return
+		}
+	}
+}
+
+// compressFiles 压缩队列中的文件
+func (f *File) compressFiles() {
+	f.lock.Lock()
+	names := f.compressQueue
+	f.compressQueue = nil
+	f.lock.Unlock()
+	for _, name := range names {
+		err := f.compressor.compressFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// Flush 实现 Flusher 接口，立即将内存的数据同步到磁盘。
+func (f *File) Flush() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return errFileClosed
+	}
+	f.flush()
+	return nil
 }
 
 // syncLoop 运行在一个协程中。
@@ -147,29 +373,38 @@ func (f *File) syncLoop(syncDur time.Duration) {
 		f.wait.Done()
 	}()
 	checkTime := time.Now()
-	// 程序退出
-	quit := make(chan os.Signal, 1)
 	// 先检查一次过期
-	f.check(&checkTime)
-	for !f.closed {
+	if f.path == "" {
+		f.check(&checkTime)
+	}
+	if f.reopenSignal != nil {
+		defer signal.Stop(f.reopenSignal)
+	}
+	for {
 		select {
 		case now := <-syncTimer.C:
 			// 检查过期
-			if now.Sub(checkTime) > time.Hour {
+			if f.path == "" && now.Sub(checkTime) > time.Hour {
 				f.check(&checkTime)
 				checkTime = now
+				f.signalRotate()
 			}
 			// 同步时间
 			f.lock.Lock()
 			f.flush()
+			// 文件被移动或者删除了
+			if f.path != "" && f.moved() {
+				f.close()
+				f.openPath()
+			}
 			f.lock.Unlock()
 			// 计时器
 			syncTimer.Reset(syncDur)
+		case <-f.reopenSignal:
+			f.Reopen()
 		case <-f.exit:
 			// 退出信号
 			return
-		case <-quit:
-			return
 		}
 	}
 }
@@ -183,17 +418,79 @@ func (f *File) Close() error {
 	}
 	f.closed = true
 	f.lock.Unlock()
+	UnregisterFlusher(f)
 	// 结束协程通知。
 	close(f.exit)
-	// 等待退出。
+	// 等待退出，协程会同步数据，并关闭文件。
 	f.wait.Wait()
-	// 同步数据，并关闭文件。
-	f.flush()
-	f.close()
 	// 返回
 	return nil
 }
 
+// Reopen 同步内存数据，关闭当前的文件，然后重新打开，用于外部的工具切割了文件之后。
+// 只有设置了 Path 的时候才会重新打开。
+func (f *File) Reopen() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return errFileClosed
+	}
+	f.flush()
+	if f.path != "" {
+		f.close()
+		f.openPath()
+	}
+	return nil
+}
+
+// rotateNonEmpty 同步数据，当前的文件不是空的时候换新文件，
+// 返回新的当前文件的路径和是否换了新文件，Net 重放 Spool 的时候使用。
+func (f *File) rotateNonEmpty() (string, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.flush()
+	rotated := false
+	if f.file != nil {
+		if info, err := f.file.Stat(); err != nil || info.Size() > 0 {
+			f.rotate()
+			rotated = true
+		}
+	}
+	if f.file == nil {
+		return "", rotated
+	}
+	return f.file.Name(), rotated
+}
+
+// openPath 打开固定路径的文件
+func (f *File) openPath() {
+	err := os.MkdirAll(filepath.Dir(f.path), os.ModePerm)
+	if nil != err {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	f.file, err = os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
+	if nil != err {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// moved 返回当前打开的文件是否已经不是 path ，被移动或者删除了
+func (f *File) moved() bool {
+	if f.file == nil {
+		return true
+	}
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return true
+	}
+	cur, err := f.file.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(info, cur)
+}
+
 // check 检查过期文件。
 func (f *File) check(now *time.Time) {
 	// 读取根目录下的所有文件
@@ -207,6 +504,10 @@ func (f *File) check(now *time.Time) {
 	// 循环检查
 	for i := 0; i < len(dirEntries); i++ {
 		entry := dirEntries[i]
+		// 没有日期目录的时候，只检查日志文件
+		if f.flat && (entry.IsDir() || !f.namer.matchCompressed(entry.Name())) {
+			continue
+		}
 		fi, err := entry.Info()
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
@@ -231,19 +532,55 @@ func (f *File) flush() {
 	f.data = f.data[:0]
 }
 
+// now 返回当前时间，本地或者 UTC
+func (f *File) now() time.Time {
+	if f.utc {
+		return time.Now().UTC()
+	}
+	return time.Now()
+}
+
+// next 返回 now 之后下一次换新文件的时间，
+// 从 now 当天的零点开始，每隔 rotateDur 一次，最晚是第二天的零点
+func (f *File) next(now time.Time) time.Time {
+	year, month, day := now.Date()
+	tomorrow := time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+	next := f.start(now).Add(f.rotateDur)
+	if next.After(tomorrow) {
+		return tomorrow
+	}
+	return next
+}
+
+// start 返回 now 所在的换新文件时间段的开始时间
+func (f *File) start(now time.Time) time.Time {
+	year, month, day := now.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	return midnight.Add(now.Sub(midnight) / f.rotateDur * f.rotateDur)
+}
+
+// dir 返回 now 的日志文件所在的目录，root/date 或者 root
+func (f *File) dir(now time.Time) string {
+	if f.flat {
+		return f.rootDir
+	}
+	return filepath.Join(f.rootDir, now.Format(dirNameFormat))
+}
+
 // open 打开一个新的文件
 func (f *File) open() {
-	now := time.Now()
+	now := f.now()
+	f.nextRotate = f.next(now)
 	// 创建目录，root/date
-	dateDir := filepath.Join(f.rootDir, now.Format(dirNameFormat))
-	err := os.MkdirAll(dateDir, os.ModePerm)
+	dir := f.dir(now)
+	err := os.MkdirAll(dir, os.ModePerm)
 	if nil != err {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
-	// 创建日志文件，root/date/time.ms
-	timeFile := filepath.Join(dateDir, now.Format(fileNameFormat))
-	f.file, err = os.OpenFile(timeFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
+	// 创建日志文件，root/date/fileName
+	path := filepath.Join(dir, f.namer.next(dir, now))
+	f.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
 	if nil != err {
 		fmt.Fprintln(os.Stderr, err)
 	}
@@ -259,59 +596,67 @@ func (f *File) close() {
 
 // openLast 打开上一个最新的文件
 func (f *File) openLast() {
-	now := time.Now()
+	now := f.now()
+	f.nextRotate = f.next(now)
 	// 创建目录，root/date
-	dateDir := filepath.Join(f.rootDir, now.Format(dirNameFormat))
+	dateDir := f.dir(now)
 	err := os.MkdirAll(dateDir, os.ModePerm)
 	if nil != err {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
-	// 读取根目录下的所有文件
+	// 读取目录下的所有文件
 	dirEntries, err := os.ReadDir(dateDir)
 	if nil != err {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
 	// 没有文件
-	fileName := now.Format(fileNameFormat)
-	if len(dirEntries) > 0 {
-		// 循环检查
-		dirEntry := dirEntries[0]
-		lastFI, err := dirEntry.Info()
+	fileName := f.namer.next(dateDir, now)
+	var lastFI os.FileInfo
+	var plains []string
+	// 找出最新的文件时间，跳过压缩的文件和其他的文件
+	for i := 0; i < len(dirEntries); i++ {
+		dirEntry := dirEntries[i]
+		if dirEntry.IsDir() || isCompressed(dirEntry.Name()) || !f.namer.match(dirEntry.Name()) {
+			continue
+		}
+		fi, err := dirEntry.Info()
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-		} else {
-			lastTime := lastFI.ModTime()
-			// 找出最新的文件时间
-			for i := 1; i < len(dirEntries); i++ {
-				dirEntry := dirEntries[i]
-				fi, err := dirEntry.Info()
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					continue
-				}
-				m := fi.ModTime()
-				if m.After(lastTime) {
-					lastTime = m
-					lastFI = fi
-				}
-			}
-			// 最新的大小
-			if lastFI.Size() < int64(f.maxFileSize) {
-				fileName = lastFI.Name()
+			continue
+		}
+		plains = append(plains, fi.Name())
+		if lastFI == nil || fi.ModTime().After(lastFI.ModTime()) ||
+			(fi.ModTime().Equal(lastFI.ModTime()) && fileNameLess(lastFI.Name(), fi.Name())) {
+			lastFI = fi
+		}
+	}
+	// 最新的大小，而且在当前的换新文件时间段内
+	if lastFI != nil &&
+		lastFI.Size() < int64(f.maxFileSize) &&
+		!lastFI.ModTime().Before(f.start(now)) {
+		fileName = lastFI.Name()
+	}
+	// 其他没有压缩的文件，放到压缩队列
+	if f.compressor != nil {
+		for _, name := range plains {
+			if name != fileName {
+				f.compressQueue = append(f.compressQueue, filepath.Join(dateDir, name))
 			}
 		}
 	}
-	// 创建日志文件，root/date/time.ms
+	// 创建日志文件，root/date/fileName
 	timeFile := filepath.Join(dateDir, fileName)
 	f.file, err = os.OpenFile(timeFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
 	if nil != err {
 		fmt.Fprintln(os.Stderr, err)
+		return
 	}
 	fi, err := f.file.Stat()
 	if nil != err {
 		fmt.Fprintln(os.Stderr, err)
+		return
 	}
 	f.curFileSize = int(fi.Size())
 }
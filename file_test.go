@@ -0,0 +1,395 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_FileNext(t *testing.T) {
+	f := &File{rotateDur: time.Hour}
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !f.next(now).Equal(time.Date(2023, 1, 2, 4, 0, 0, 0, time.UTC)) {
+		t.Fatal(f.next(now))
+	}
+	// 每天
+	f.rotateDur = 24 * time.Hour
+	if !f.next(now).Equal(time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal(f.next(now))
+	}
+	// 自定义，最晚是零点
+	f.rotateDur = 7 * time.Hour
+	if !f.next(now).Equal(time.Date(2023, 1, 2, 7, 0, 0, 0, time.UTC)) {
+		t.Fatal(f.next(now))
+	}
+	now = time.Date(2023, 1, 2, 22, 0, 0, 0, time.UTC)
+	if !f.next(now).Equal(time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal(f.next(now))
+	}
+}
+
+func Test_FileRotate(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFile(&FileConfig{
+		RootDir:      dir,
+		MaxFileSize:  "1M",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+		Rotate:       "hourly",
+		UTC:          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("1\n"))
+	// 到了时间
+	f.lock.Lock()
+	f.nextRotate = time.Now().Add(-time.Second)
+	f.lock.Unlock()
+	time.Sleep(time.Millisecond)
+	f.Write([]byte("2\n"))
+	f.Close()
+	dateDir := filepath.Join(dir, time.Now().UTC().Format(dirNameFormat))
+	entries, err := os.ReadDir(dateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatal(entries)
+	}
+	for i, entry := range entries {
+		d, err := os.ReadFile(filepath.Join(dateDir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d) != []string{"1\n", "2\n"}[i] {
+			t.Fatal(string(d))
+		}
+	}
+}
+
+func Test_FileCompress(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFile(&FileConfig{
+		RootDir:      dir,
+		MaxFileSize:  "4",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+		Compress:     "gzip",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每一行都会换新文件
+	for i := 0; i < 3; i++ {
+		f.Write([]byte("123\n"))
+		time.Sleep(time.Millisecond)
+	}
+	f.Close()
+	dateDir := filepath.Join(dir, time.Now().Format(dirNameFormat))
+	entries, err := os.ReadDir(dateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 个压缩的文件，1 个空的当前文件
+	n := 0
+	for _, entry := range entries {
+		name := filepath.Join(dateDir, entry.Name())
+		if filepath.Ext(name) != ".gz" {
+			fi, _ := entry.Info()
+			if fi.Size() != 0 {
+				t.Fatal(name)
+			}
+			continue
+		}
+		n++
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := io.ReadAll(r)
+		file.Close()
+		if err != nil || string(d) != "123\n" {
+			t.Fatal(err, string(d))
+		}
+	}
+	if n != 3 {
+		t.Fatal(entries)
+	}
+	// 不存在的压缩
+	_, err = NewFile(&FileConfig{RootDir: dir, MaxFileSize: "1M", Compress: "none"})
+	if err == nil {
+		t.FailNow()
+	}
+}
+
+func Test_FileLimit(t *testing.T) {
+	dir := t.TempDir()
+	// 旧的目录
+	oldDir := filepath.Join(dir, "20000101")
+	os.MkdirAll(oldDir, os.ModePerm)
+	os.WriteFile(filepath.Join(oldDir, "20000101000000.000000"), []byte("old\n"), os.ModePerm)
+	os.Chtimes(filepath.Join(oldDir, "20000101000000.000000"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	f, err := NewFile(&FileConfig{
+		RootDir:      dir,
+		MaxFileSize:  "4",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+		MaxFiles:     2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("123\n"))
+		time.Sleep(time.Millisecond)
+	}
+	f.Close()
+	files, err := f.listFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatal(len(files))
+	}
+	// 空的旧目录也删除了
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	// 总大小
+	dir = t.TempDir()
+	f, err = NewFile(&FileConfig{
+		RootDir:      dir,
+		MaxFileSize:  "4",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+		MaxTotalSize: "10",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("123\n"))
+		time.Sleep(time.Millisecond)
+	}
+	f.Close()
+	files, err = f.listFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	if total > 10 {
+		t.Fatal(total)
+	}
+}
+
+func Test_FilePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app", "app.log")
+	f, err := NewFile(&FileConfig{
+		Path:         path,
+		MaxFileSize:  "1M",
+		SyncInterval: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("1\n"))
+	f.Flush()
+	// logrotate 移动之后重新打开
+	err = os.Rename(path, path+".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("2\n"))
+	f.Reopen()
+	f.Write([]byte("3\n"))
+	f.Flush()
+	// 删除之后，同步的时候自动重新打开
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(minSyncDur * 3)
+	f.Write([]byte("4\n"))
+	f.Close()
+	for name, s := range map[string]string{
+		path + ".1": "1\n2\n",
+		path:        "4\n",
+	} {
+		d, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d) != s {
+			t.Fatalf("%s %q", name, d)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app", time.Now().Format(dirNameFormat))); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if f.Reopen() != errFileClosed {
+		t.FailNow()
+	}
+	// 固定路径不需要 RootDir 和 MaxKeepDay
+	if validateConfig(&FileConfig{Path: path, SyncInterval: 10}) != nil ||
+		validateConfig(&FileConfig{SyncInterval: 10}) == nil {
+		t.FailNow()
+	}
+}
+
+func Test_FileName(t *testing.T) {
+	n, err := newFileNamer("{app}-{date}-{seq}.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := n.format(now, 1)
+	if name != AppName+"-20230102-1.log" || !n.match(name) ||
+		!n.matchCompressed(name+".gz") || n.match(name+".gz") || n.match("x.log") {
+		t.Fatal(name)
+	}
+	// 默认
+	n, err = newFileNamer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.format(now, 0) != "20230102030405.000000" {
+		t.FailNow()
+	}
+	for _, p := range []string{"{x}", "{time", "a/{seq}"} {
+		if _, err := newFileNamer(p); err == nil {
+			t.Fatal(p)
+		}
+	}
+	// 没有日期目录，按大小换新文件
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("other"), os.ModePerm)
+	f, err := NewFile(&FileConfig{
+		RootDir:      dir,
+		FileName:     "app-{seq}.log",
+		Layout:       "flat",
+		MaxFileSize:  "4",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+		MaxFiles:     2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		f.Write([]byte("12" + strconv.Itoa(i) + "\n"))
+	}
+	f.Close()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	// 最旧的 app-0.log 和 app-1.log 被删除，其他的文件不变，app-3.log 是换新的空文件
+	if strings.Join(names, ",") != "app-2.log,app-3.log,other.txt" {
+		t.Fatal(names)
+	}
+	// 重新打开最新的文件
+	f, err = NewFile(&FileConfig{
+		RootDir:      dir,
+		FileName:     "app-{seq}.log",
+		Layout:       "flat",
+		MaxFileSize:  "1M",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("3\n"))
+	f.Close()
+	d, _ := os.ReadFile(filepath.Join(dir, "app-3.log"))
+	if string(d) != "3\n" {
+		t.Fatalf("%q", d)
+	}
+}
+
+func Test_FileNameLayout(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	for _, layout := range []string{
+		"2006", "2006-01-02T15", "Jan _2 Monday 3PM", "20060102.000", "150405.999", "2006_2006-07:00 MST", "Z0700",
+	} {
+		n, err := newFileNamer("{time:" + layout + "}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := n.format(now, 0); !n.match(name) || n.match("other.txt") {
+			t.Fatal(layout, name)
+		}
+	}
+	// 只有时间的格式，不删除目录中其他的文件
+	dir := t.TempDir()
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	for _, name := range []string{"2020010100", "other.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := NewFile(&FileConfig{
+		RootDir:      dir,
+		FileName:     "{time:2006010215}",
+		Layout:       "flat",
+		MaxFileSize:  "1M",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+		MaxFiles:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("1\n"))
+	f.Close()
+	if _, err := os.Stat(filepath.Join(dir, "other.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2020010100")); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
+
+func Test_FileOpenError(t *testing.T) {
+	// 文件的路径是一个目录，打开失败
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "app.log"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(&FileConfig{
+		RootDir:      dir,
+		FileName:     "app.log",
+		MaxFileSize:  "1M",
+		Layout:       "flat",
+		MaxKeepDay:   1,
+		SyncInterval: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("1\n"))
+	f.Close()
+}
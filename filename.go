@@ -0,0 +1,269 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 默认的文件名格式
+	defaultFileName = "{time}"
+)
+
+// fileNamePart 是文件名格式的一部分
+type fileNamePart struct {
+	// 文本
+	text string
+	// 时间的格式
+	layout string
+	// 序号
+	seq bool
+}
+
+// fileNamer 按照 FileConfig.FileName 生成和匹配文件名
+type fileNamer struct {
+	parts []fileNamePart
+	// 是否有序号
+	seq bool
+	// 匹配生成的文件名
+	re *regexp.Regexp
+}
+
+// newFileNamer 解析文件名格式
+//
+//	{date} 20060102
+//	{time} 20060102150405.000000
+//	{time:layout} time.Format 的 layout ，比如 {time:2006-01-02T15}
+//	{seq} 同一个目录中的序号，从 0 开始，已经存在的文件名会跳过
+//	{app} {host} {pid} AppName ，主机名和进程 ID
+//
+// 其他的是文本，比如 "{app}-{date}-{seq}.log" 。
+func newFileNamer(pattern string) (*fileNamer, error) {
+	if pattern == "" {
+		pattern = defaultFileName
+	}
+	n := new(fileNamer)
+	re := "^"
+	for pattern != "" {
+		i := strings.IndexByte(pattern, '{')
+		if i < 0 {
+			i = len(pattern)
+		}
+		// 文本
+		if i > 0 {
+			if strings.ContainsAny(pattern[:i], `/\`) {
+				return nil, fmt.Errorf("file name: %q contains path separator", pattern[:i])
+			}
+			n.parts = append(n.parts, fileNamePart{text: pattern[:i]})
+			re += regexp.QuoteMeta(pattern[:i])
+			pattern = pattern[i:]
+			continue
+		}
+		j := strings.IndexByte(pattern, '}')
+		if j < 0 {
+			return nil, fmt.Errorf("file name: unclosed {")
+		}
+		token := pattern[1:j]
+		pattern = pattern[j+1:]
+		name, arg, _ := strings.Cut(token, ":")
+		var part fileNamePart
+		switch name {
+		case "date":
+			part.layout = dirNameFormat
+			re += `\d{8}`
+		case "time":
+			if arg == "" {
+				part.layout = fileNameFormat
+				re += `\d{14}\.\d{6}`
+			} else {
+				part.layout = arg
+				re += layoutRegexp(arg)
+			}
+		case "seq":
+			part.seq = true
+			n.seq = true
+			re += `\d+`
+		case "app":
+			part.text = AppName
+			re += regexp.QuoteMeta(part.text)
+		case "host":
+			host, err := os.Hostname()
+			if err != nil {
+				return nil, err
+			}
+			part.text = host
+			re += regexp.QuoteMeta(part.text)
+		case "pid":
+			part.text = strconv.Itoa(os.Getpid())
+			re += regexp.QuoteMeta(part.text)
+		default:
+			return nil, fmt.Errorf("file name: unknown token %q", token)
+		}
+		n.parts = append(n.parts, part)
+	}
+	re += "$"
+	var err error
+	n.re, err = regexp.Compile(re)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// layoutRegexp 返回匹配 time.Format 使用 layout 输出的正则表达式，
+// 只匹配每一个元素可能输出的字符，这样不会匹配到目录中其他的文件
+func layoutRegexp(layout string) string {
+	var re, text strings.Builder
+	for i := 0; i < len(layout); {
+		n, elem := layoutElem(layout[i:])
+		if n == 0 {
+			text.WriteByte(layout[i])
+			i++
+			continue
+		}
+		re.WriteString(regexp.QuoteMeta(text.String()))
+		text.Reset()
+		re.WriteString(elem)
+		i += n
+	}
+	re.WriteString(regexp.QuoteMeta(text.String()))
+	return re.String()
+}
+
+// layoutElem 返回 s 开头的 layout 元素的长度和它的正则表达式，不是元素返回 0
+func layoutElem(s string) (int, string) {
+	for _, e := range [...]struct {
+		elem string
+		re   string
+	}{
+		{"January", `[A-Z][a-z]+`},
+		{"Jan", `[A-Z][a-z]{2}`},
+		{"Monday", `[A-Z][a-z]+`},
+		{"Mon", `[A-Z][a-z]{2}`},
+		{"MST", `(?:[A-Z]{3,5}|[+-]\d{2,4})`},
+		{"2006", `\d{4}`},
+		{"002", `\d{3}`},
+		{"__2", `[ \d]{2}\d`},
+		// "_2006" 是 "_" 和年
+		{"_2006", `_\d{4}`},
+		{"_2", `[ \d]\d`},
+		{"01", `\d{2}`},
+		{"02", `\d{2}`},
+		{"03", `\d{2}`},
+		{"04", `\d{2}`},
+		{"05", `\d{2}`},
+		{"06", `\d{2}`},
+		{"15", `\d{2}`},
+		{"1", `\d{1,2}`},
+		{"2", `\d{1,2}`},
+		{"3", `\d{1,2}`},
+		{"4", `\d{1,2}`},
+		{"5", `\d{1,2}`},
+		{"PM", `[AP]M`},
+		{"pm", `[ap]m`},
+		{"Z070000", `(?:Z|[+-]\d{6})`},
+		{"Z07:00:00", `(?:Z|[+-]\d{2}:\d{2}:\d{2})`},
+		{"Z0700", `(?:Z|[+-]\d{4})`},
+		{"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`},
+		{"Z07", `(?:Z|[+-]\d{2})`},
+		{"-070000", `[+-]\d{6}`},
+		{"-07:00:00", `[+-]\d{2}:\d{2}:\d{2}`},
+		{"-0700", `[+-]\d{4}`},
+		{"-07:00", `[+-]\d{2}:\d{2}`},
+		{"-07", `[+-]\d{2}`},
+	} {
+		if strings.HasPrefix(s, e.elem) {
+			return len(e.elem), e.re
+		}
+	}
+	// 秒的小数，".000" 固定位数，".999" 去掉末尾的 0 ，可能没有
+	if len(s) > 1 && (s[0] == '.' || s[0] == ',') && (s[1] == '0' || s[1] == '9') {
+		n := 1
+		for n < len(s) && s[n] == s[1] {
+			n++
+		}
+		if n == len(s) || s[n] < '0' || s[n] > '9' {
+			sep := regexp.QuoteMeta(s[:1])
+			if s[1] == '0' {
+				return n, sep + `\d{` + strconv.Itoa(n-1) + `}`
+			}
+			return n, `(?:` + sep + `\d{1,` + strconv.Itoa(n-1) + `})?`
+		}
+	}
+	return 0, ""
+}
+
+// format 返回 now 和 seq 的文件名
+func (n *fileNamer) format(now time.Time, seq int) string {
+	var b []byte
+	for _, p := range n.parts {
+		switch {
+		case p.layout != "":
+			b = now.AppendFormat(b, p.layout)
+		case p.seq:
+			b = strconv.AppendInt(b, int64(seq), 10)
+		default:
+			b = append(b, p.text...)
+		}
+	}
+	return string(b)
+}
+
+// next 返回 dir 目录中 now 的新文件名，有序号的时候跳过已经存在的文件
+func (n *fileNamer) next(dir string, now time.Time) string {
+	for seq := 0; ; seq++ {
+		name := n.format(now, seq)
+		if !n.seq || !logFileExists(filepath.Join(dir, name)) {
+			return name
+		}
+	}
+}
+
+// match 返回 name 是否是这个格式的文件名，不包括压缩的文件
+func (n *fileNamer) match(name string) bool {
+	return n.re.MatchString(name)
+}
+
+// matchCompressed 返回 name 是否是这个格式的文件名，或者它压缩后的文件，或者压缩中的临时文件
+func (n *fileNamer) matchCompressed(name string) bool {
+	if n.match(name) {
+		return true
+	}
+	name = strings.TrimSuffix(name, compressTempExt)
+	compressorLock.RLock()
+	defer compressorLock.RUnlock()
+	for _, c := range compressors {
+		if s, ok := strings.CutSuffix(name, c.ext); ok && n.match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileNameLess 比较修改时间相同的文件名，短的在前面，这样 {seq} 的 9 在 10 的前面
+func fileNameLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// logFileExists 返回日志文件 path 或者它压缩后的文件是否存在
+func logFileExists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	compressorLock.RLock()
+	defer compressorLock.RUnlock()
+	for _, c := range compressors {
+		if _, err := os.Stat(path + c.ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
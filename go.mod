@@ -1,3 +1,5 @@
 module github.com/qq51529210/log
 
-go 1.20
+go 1.21
+
+require gopkg.in/yaml.v3 v3.0.1
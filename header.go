@@ -3,7 +3,6 @@ package log
 import (
 	"os"
 	"runtime"
-	"time"
 )
 
 var (
@@ -11,28 +10,10 @@ var (
 	headerEnd = []byte(": ")
 )
 
-// FormatTime 格式化 "2006-01-02 15:04:05.000000"
+// FormatTime 使用本地时间格式化 "2006-01-02 15:04:05.000000000" ，
+// 其他格式使用 TimeFormatter
 func FormatTime(log *Log) {
-	// 不使用 time 标准库，快一点
-	t := time.Now()
-	year, month, day := t.Date()
-	hour, minute, second := t.Clock()
-	// Date
-	log.IntLeftAlign(year, 4)
-	log.b = append(log.b, '-')
-	log.IntRightAlign(int(month), 2)
-	log.b = append(log.b, '-')
-	log.IntRightAlign(day, 2)
-	log.b = append(log.b, ' ')
-	// Time
-	log.IntRightAlign(hour, 2)
-	log.b = append(log.b, ':')
-	log.IntRightAlign(minute, 2)
-	log.b = append(log.b, ':')
-	log.IntRightAlign(second, 2)
-	// Nanosecond
-	log.b = append(log.b, '.')
-	log.IntLeftAlign(t.Nanosecond(), 9)
+	defaultTimeFormatter.Format(log)
 }
 
 // FormatHeader 用于格式化日志头
@@ -44,43 +25,64 @@ func DefaultHeader(log *Log, depth int) {
 	// log.b = append(log.b, headerEnd...)
 }
 
-// FileNameHeader 输出 2006-01-02 15:04:05.000000000 [fileName:fileLine]
+// FileNameHeader 输出 2006-01-02 15:04:05.000000000 fileName:fileLine
 func FileNameHeader(log *Log, depth int) {
 	// 2006-01-02 15:04:05.000000000
 	FormatTime(log)
 	log.b = append(log.b, ' ')
-	// [fileName:fileLine]
-	_, path, line, ok := runtime.Caller(depth)
-	if !ok {
-		path = "???"
-		line = -1
-	} else {
-		for i := len(path) - 1; i > 0; i-- {
-			if os.IsPathSeparator(path[i]) {
-				path = path[i+1:]
-				break
-			}
-		}
-	}
-	log.b = append(log.b, path...)
-	log.b = append(log.b, ':')
-	log.Int(line)
-	// log.b = append(log.b, headerEnd...)
+	// fileName:fileLine
+	writeCaller(log, depth+1, false)
 }
 
-// FilePathHeader 输出 2006-01-02 15:04:05.000000 [filePath:fileLine]
+// FilePathHeader 输出 2006-01-02 15:04:05.000000000 filePath:fileLine
 func FilePathHeader(log *Log, depth int) {
 	// 2006-01-02 15:04:05.000000000
 	FormatTime(log)
 	log.b = append(log.b, ' ')
-	// [filePath:fileLine]
-	_, path, line, ok := runtime.Caller(depth)
+	// filePath:fileLine
+	writeCaller(log, depth+1, true)
+}
+
+// writeCaller 写入 path:line ，fullPath 是否使用完整路径，否则只有文件名，
+// depth 和 FormatHeader 的参数相同
+func writeCaller(log *Log, depth int, fullPath bool) {
+	path, line, ok := log.caller(depth)
 	if !ok {
 		path = "???"
 		line = -1
+	} else if !fullPath {
+		path = trimPath(path)
 	}
 	log.b = append(log.b, path...)
 	log.b = append(log.b, ':')
 	log.Int(line)
-	// log.b = append(log.b, headerEnd...)
+}
+
+// trimPath 返回路径中的文件名
+func trimPath(path string) string {
+	for i := len(path) - 1; i > 0; i-- {
+		if os.IsPathSeparator(path[i]) {
+			return path[i+1:]
+		}
+	}
+	return path
+}
+
+// caller 返回调用者的文件和行号，如果有 pc 使用 pc ，否则使用 depth
+// depth 和 FormatHeader 的参数相同
+func (l *Log) caller(depth int) (string, int, bool) {
+	if l.pc != 0 {
+		return pcCaller(l.pc)
+	}
+	_, path, line, ok := runtime.Caller(depth + 1)
+	return path, line, ok
+}
+
+// pcCaller 返回 pc 的文件和行号，使用缓存
+func pcCaller(pc uintptr) (string, int, bool) {
+	f := pcFrame(pc)
+	if f.Line < 0 {
+		return "", 0, false
+	}
+	return f.File, f.Line, true
 }
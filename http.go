@@ -0,0 +1,674 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 默认的参数
+	defaultHTTPBatchSize  = 1024 * 1024
+	defaultHTTPBatchCount = 1000
+	defaultHTTPLinger     = time.Second
+	defaultHTTPQueueSize  = 10000
+	defaultHTTPRetry      = 3
+	defaultHTTPBackoff    = 100 * time.Millisecond
+	defaultHTTPMaxBackoff = 30 * time.Second
+	defaultHTTPTimeout    = 10 * time.Second
+	defaultHTTPIndex      = "logs"
+)
+
+var (
+	errHTTPClosed = errors.New("http has been closed")
+)
+
+// HTTPError 是服务端返回的错误状态
+type HTTPError struct {
+	// 状态码
+	StatusCode int
+	// 响应的内容，最多 1K
+	Body string
+}
+
+func (e *HTTPError) Error() string {
+	return "http status " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+// retryable 返回是否可以重试，429 和 5xx
+func (e *HTTPError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// HTTPConfig 是 NewHTTP 的参数。
+type HTTPConfig struct {
+	// 地址，比如 loki 的 http://loki:3100/loki/api/v1/push ，
+	// elasticsearch 的 http://es:9200/_bulk
+	URL string `json:"url" yaml:"url" validate:"required"`
+	// 格式，loki/elasticsearch/json ，默认是 json ，一个批次是一个 JSON 数组
+	Format string `json:"format" yaml:"format" validate:"omitempty,oneof=loki elasticsearch json"`
+	// 请求头，比如 Authorization 和 X-Scope-OrgID
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	// 标签，name/level/host ，是 Logger 的名称，级别和主机名，
+	// loki 是 stream 的标签，其他的是每一行的字段
+	Labels []string `json:"labels" yaml:"labels"`
+	// 固定的标签
+	StaticLabels map[string]string `json:"staticLabels" yaml:"staticLabels"`
+	// elasticsearch 的索引，默认是 logs
+	Index string `json:"index" yaml:"index"`
+	// 是否使用 gzip 压缩请求
+	Gzip bool `json:"gzip" yaml:"gzip"`
+	// 每一个批次的最大字节，使用 1.5/K/M/G/T 这样的字符表示，默认是 1M
+	BatchSize string `json:"batchSize" yaml:"batchSize"`
+	// 每一个批次的最大行数，默认是 1000
+	BatchCount int `json:"batchCount" yaml:"batchCount" validate:"omitempty,min=1"`
+	// 批次等待的时间，单位毫秒，默认是 1000
+	Linger int `json:"linger" yaml:"linger" validate:"omitempty,min=1"`
+	// 内存队列的最大行数，默认是 10000
+	QueueSize int `json:"queueSize" yaml:"queueSize" validate:"omitempty,min=1"`
+	// 队列满的时候丢弃 newest/oldest ，默认是 newest
+	Drop string `json:"drop" yaml:"drop" validate:"omitempty,oneof=newest oldest"`
+	// 网络错误，429 和 5xx 的重试次数，默认是 3 ，小于 0 不重试
+	Retry int `json:"retry" yaml:"retry"`
+	// 第一次重试的等待时间，单位毫秒，之后每次翻倍，默认是 100 ，
+	// 429 和 503 有 Retry-After 的时候使用它
+	Backoff int `json:"backoff" yaml:"backoff" validate:"omitempty,min=1"`
+	// 重试的最大等待时间，单位毫秒，默认是 30000
+	MaxBackoff int `json:"maxBackoff" yaml:"maxBackoff" validate:"omitempty,min=1"`
+	// 请求的超时，单位毫秒，默认是 10000
+	Timeout int `json:"timeout" yaml:"timeout" validate:"omitempty,min=1"`
+}
+
+// httpMessage 是队列中的一行日志
+type httpMessage struct {
+	line  []byte
+	time  time.Time
+	level Level
+	name  string
+	trace string
+	// OTLP 使用，line 是 Entry.Message
+	fields []Field
+	pc     uintptr
+}
+
+// httpLabel 是一个标签
+type httpLabel struct {
+	key   string
+	value string
+}
+
+// NewHTTP 返回一个 HTTP 实例。
+func NewHTTP(conf *HTTPConfig) (*HTTP, error) {
+	format := conf.Format
+	if format == "" {
+		format = "json"
+	}
+	switch format {
+	case "loki", "elasticsearch", "json":
+	default:
+		return nil, fmt.Errorf("unknown http format %q", conf.Format)
+	}
+	return newHTTP(conf, format, nil)
+}
+
+// newHTTP 是 NewHTTP 的实现，otlp 不为 nil 的时候使用 OTLP 的格式
+func newHTTP(conf *HTTPConfig, format string, otlp *otlpEncoder) (*HTTP, error) {
+	if conf.URL == "" {
+		return nil, errors.New("http url is empty")
+	}
+	h := new(HTTP)
+	h.url = conf.URL
+	h.format = format
+	h.otlp = otlp
+	h.headers = make(http.Header)
+	for k, v := range conf.Headers {
+		h.headers.Set(k, v)
+	}
+	// 标签
+	for _, l := range conf.Labels {
+		switch l {
+		case "name":
+			h.labelName = true
+		case "level":
+			h.labelLevel = true
+		case "host":
+			host, err := os.Hostname()
+			if err != nil {
+				return nil, err
+			}
+			h.static = append(h.static, httpLabel{"host", host})
+		default:
+			return nil, fmt.Errorf("unknown http label %q", l)
+		}
+	}
+	for k, v := range conf.StaticLabels {
+		h.static = append(h.static, httpLabel{k, v})
+	}
+	sort.Slice(h.static, func(i, j int) bool {
+		return h.static[i].key < h.static[j].key
+	})
+	h.index = conf.Index
+	if h.index == "" {
+		h.index = defaultHTTPIndex
+	}
+	h.gzip = conf.Gzip
+	// 批次
+	h.batchSize = defaultHTTPBatchSize
+	if conf.BatchSize != "" {
+		size, err := ParseSize(conf.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if size > 0 {
+			h.batchSize = int(size)
+		}
+	}
+	h.batchCount = conf.BatchCount
+	if h.batchCount < 1 {
+		h.batchCount = defaultHTTPBatchCount
+	}
+	h.linger = time.Duration(conf.Linger) * time.Millisecond
+	if h.linger <= 0 {
+		h.linger = defaultHTTPLinger
+	}
+	// 队列
+	queueSize := conf.QueueSize
+	if queueSize < 1 {
+		queueSize = defaultHTTPQueueSize
+	}
+	h.queue = make(chan *httpMessage, queueSize)
+	h.dropOldest = conf.Drop == "oldest"
+	// 重试
+	h.retry = conf.Retry
+	if h.retry == 0 {
+		h.retry = defaultHTTPRetry
+	} else if h.retry < 0 {
+		h.retry = 0
+	}
+	h.backoff = time.Duration(conf.Backoff) * time.Millisecond
+	if h.backoff <= 0 {
+		h.backoff = defaultHTTPBackoff
+	}
+	h.maxBackoff = time.Duration(conf.MaxBackoff) * time.Millisecond
+	if h.maxBackoff <= 0 {
+		h.maxBackoff = defaultHTTPMaxBackoff
+	}
+	timeout := time.Duration(conf.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	h.client = &http.Client{Timeout: timeout}
+	h.exit = make(chan struct{})
+	h.flushReq = make(chan chan struct{})
+	// 启动发送协程
+	h.wait.Add(1)
+	go h.sendLoop()
+	return h, nil
+}
+
+// HTTP 实现了 io.Writer 和 EntryWriter 接口，可以作为 Logger 的输出。
+// 日志先进入内存队列，后台协程按照行数，字节数和等待时间组成批次，
+// 编码成 loki push ，elasticsearch _bulk 或者 JSON 数组，POST 到服务端。
+// 队列满的时候会丢弃日志，网络错误，429 和 5xx 会重试，重试失败或者其他的错误丢弃日志。
+type HTTP struct {
+	lock sync.Mutex
+	wait sync.WaitGroup
+	// 退出协程通知
+	exit chan struct{}
+	// Flush 请求
+	flushReq chan chan struct{}
+	// 是否已关闭标志
+	closed bool
+	// 队列
+	queue chan *httpMessage
+	// 队列满的时候是否丢弃最旧的
+	dropOldest bool
+	// 丢弃的行数
+	dropped atomic.Uint64
+	// 参数
+	url        string
+	format     string
+	headers    http.Header
+	labelName  bool
+	labelLevel bool
+	static     []httpLabel
+	index      string
+	gzip       bool
+	batchSize  int
+	batchCount int
+	linger     time.Duration
+	retry      int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	client     *http.Client
+	// OTLP 的编码，format 是 otlp 和 otlp-json 的时候使用
+	otlp *otlpEncoder
+	// 以下只在发送协程使用
+	// 编码缓存
+	body Log
+	zbuf bytes.Buffer
+	zw   *gzip.Writer
+}
+
+// Write 实现 io.Writer ，从 "[name] [level] " 前缀中解析级别。
+func (h *HTTP) Write(b []byte) (int, error) {
+	return h.write(syslogLevel(b), "", "", b)
+}
+
+// WriteEntry 实现 EntryWriter ，使用 Entry 的级别，名称和追踪。
+func (h *HTTP) WriteEntry(e *Entry, b []byte) (int, error) {
+	return h.write(e.Level, e.Name, e.Trace, b)
+}
+
+// Dropped 返回丢弃的行数
+func (h *HTTP) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// write 复制数据，添加到队列
+func (h *HTTP) write(level Level, name, trace string, b []byte) (int, error) {
+	n := len(b)
+	// 去掉换行
+	if n > 0 && b[n-1] == '\n' {
+		b = b[:n-1]
+	}
+	m := &httpMessage{
+		line:  append([]byte(nil), b...),
+		time:  Now(),
+		level: level,
+		name:  name,
+		trace: trace,
+	}
+	return n, h.push(m)
+}
+
+// push 添加到队列，满了的时候丢弃
+func (h *HTTP) push(m *httpMessage) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	// 关闭了
+	if h.closed {
+		return errHTTPClosed
+	}
+	select {
+	case h.queue <- m:
+		return nil
+	default:
+	}
+	// 队列满了
+	h.dropped.Add(1)
+	if !h.dropOldest {
+		return nil
+	}
+	// 丢弃最旧的
+	select {
+	case <-h.queue:
+	default:
+	}
+	select {
+	case h.queue <- m:
+	default:
+	}
+	return nil
+}
+
+// Flush 实现 Flusher 接口，立即发送队列中的数据，等待发送完成。
+func (h *HTTP) Flush() error {
+	done := make(chan struct{})
+	h.lock.Lock()
+	if h.closed {
+		h.lock.Unlock()
+		return errHTTPClosed
+	}
+	h.lock.Unlock()
+	select {
+	case h.flushReq <- done:
+		<-done
+		return nil
+	case <-h.exit:
+		return errHTTPClosed
+	}
+}
+
+// Close 实现 io.Closer 接口，发送队列中的数据，等待协程退出。
+func (h *HTTP) Close() error {
+	h.lock.Lock()
+	if h.closed {
+		h.lock.Unlock()
+		return errHTTPClosed
+	}
+	h.closed = true
+	h.lock.Unlock()
+	// 结束协程通知。
+	close(h.exit)
+	// 等待退出。
+	h.wait.Wait()
+	return nil
+}
+
+// sendLoop 运行在一个协程中，组成批次发送。
+func (h *HTTP) sendLoop() {
+	lingerTimer := time.NewTimer(h.linger)
+	stopTimer(lingerTimer)
+	var batch []*httpMessage
+	size := 0
+	defer func() {
+		lingerTimer.Stop()
+		// 发送剩下的数据
+		h.flushAll(batch)
+		h.client.CloseIdleConnections()
+		h.wait.Done()
+	}()
+	for {
+		select {
+		case m := <-h.queue:
+			// 第一条开始计时
+			if len(batch) < 1 {
+				lingerTimer.Reset(h.linger)
+			}
+			batch = append(batch, m)
+			size += len(m.line)
+			// 达到最大，立即发送
+			if size >= h.batchSize || len(batch) >= h.batchCount {
+				stopTimer(lingerTimer)
+				h.flush(batch)
+				batch = batch[:0]
+				size = 0
+			}
+		case <-lingerTimer.C:
+			h.flush(batch)
+			batch = batch[:0]
+			size = 0
+		case done := <-h.flushReq:
+			stopTimer(lingerTimer)
+			h.flushAll(batch)
+			batch = batch[:0]
+			size = 0
+			close(done)
+		case <-h.exit:
+			return
+		}
+	}
+}
+
+// flushAll 发送 batch 和队列中所有的数据
+func (h *HTTP) flushAll(batch []*httpMessage) {
+	for {
+		select {
+		case m := <-h.queue:
+			batch = append(batch, m)
+			continue
+		default:
+		}
+		break
+	}
+	for len(batch) > 0 {
+		i := h.batchEnd(batch)
+		h.flush(batch[:i])
+		batch = batch[i:]
+	}
+}
+
+// batchEnd 返回不超过 batchSize 和 batchCount 的批次的结束位置
+func (h *HTTP) batchEnd(batch []*httpMessage) int {
+	size := 0
+	for i, m := range batch {
+		size += len(m.line)
+		if size >= h.batchSize || i+1 >= h.batchCount {
+			return i + 1
+		}
+	}
+	return len(batch)
+}
+
+// flush 发送一个批次，可以重试的错误重试，最后还是失败丢弃。
+// 退出的时候不再等待，立即最后重试一次。
+func (h *HTTP) flush(batch []*httpMessage) {
+	if len(batch) < 1 {
+		return
+	}
+	body, err := h.encode(batch)
+	if err != nil {
+		h.dropped.Add(uint64(len(batch)))
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	backoff := h.backoff
+	exiting := false
+	for i := 0; ; i++ {
+		wait, err := h.post(body)
+		if err == nil {
+			return
+		}
+		var he *HTTPError
+		if i >= h.retry || exiting || (errors.As(err, &he) && !he.retryable()) {
+			h.dropped.Add(uint64(len(batch)))
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if wait < backoff {
+			wait = backoff
+		}
+		if wait > h.maxBackoff {
+			wait = h.maxBackoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-h.exit:
+			timer.Stop()
+			exiting = true
+		}
+		backoff *= 2
+	}
+}
+
+// post 发送请求，返回 Retry-After 的时间
+func (h *HTTP) post(body []byte) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range h.headers {
+		req.Header[k] = v
+	}
+	switch h.format {
+	case "elasticsearch":
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	case "otlp":
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	default:
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if h.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		d, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		var wait time.Duration
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s > 0 {
+			wait = time.Duration(s) * time.Second
+		}
+		return wait, &HTTPError{StatusCode: res.StatusCode, Body: string(d)}
+	}
+	// elasticsearch 的部分失败不重试，避免重复
+	if h.format == "elasticsearch" {
+		var bulk struct {
+			Errors bool `json:"errors"`
+		}
+		if json.NewDecoder(res.Body).Decode(&bulk) == nil && bulk.Errors {
+			fmt.Fprintln(os.Stderr, "elasticsearch bulk has errors")
+		}
+	}
+	io.Copy(io.Discard, res.Body)
+	return 0, nil
+}
+
+// encode 编码一个批次，需要的话压缩
+func (h *HTTP) encode(batch []*httpMessage) ([]byte, error) {
+	l := &h.body
+	l.b = l.b[:0]
+	switch h.format {
+	case "loki":
+		h.encodeLoki(l, batch)
+	case "elasticsearch":
+		h.encodeBulk(l, batch)
+	case "otlp":
+		h.otlp.encodeProto(l, batch)
+	case "otlp-json":
+		h.otlp.encodeJSON(l, batch)
+	default:
+		h.encodeJSON(l, batch)
+	}
+	if !h.gzip {
+		return l.b, nil
+	}
+	h.zbuf.Reset()
+	if h.zw == nil {
+		h.zw = gzip.NewWriter(&h.zbuf)
+	} else {
+		h.zw.Reset(&h.zbuf)
+	}
+	_, err := h.zw.Write(l.b)
+	if err != nil {
+		return nil, err
+	}
+	err = h.zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return h.zbuf.Bytes(), nil
+}
+
+// labels 返回 m 的标签，按照名称排序
+func (h *HTTP) labels(m *httpMessage) []httpLabel {
+	labels := append([]httpLabel(nil), h.static...)
+	if h.labelLevel {
+		labels = append(labels, httpLabel{"level", m.level.String()})
+	}
+	if h.labelName && m.name != "" {
+		labels = append(labels, httpLabel{"name", m.name})
+	}
+	sort.SliceStable(labels, func(i, j int) bool {
+		return labels[i].key < labels[j].key
+	})
+	return labels
+}
+
+// encodeLoki 编码成 loki push 的格式，相同标签的在一个 stream
+//
+//	{"streams":[{"stream":{"k":"v"},"values":[["ns","line"]]}]}
+func (h *HTTP) encodeLoki(l *Log, batch []*httpMessage) {
+	type stream struct {
+		labels []httpLabel
+		values []*httpMessage
+	}
+	var streams []*stream
+	keys := make(map[string]*stream)
+	var key strings.Builder
+	for _, m := range batch {
+		labels := h.labels(m)
+		key.Reset()
+		for _, lb := range labels {
+			key.WriteString(lb.key)
+			key.WriteByte(0)
+			key.WriteString(lb.value)
+			key.WriteByte(0)
+		}
+		s := keys[key.String()]
+		if s == nil {
+			s = &stream{labels: labels}
+			keys[key.String()] = s
+			streams = append(streams, s)
+		}
+		s.values = append(s.values, m)
+	}
+	l.b = append(l.b, `{"streams":[`...)
+	for i, s := range streams {
+		if i > 0 {
+			l.b = append(l.b, ',')
+		}
+		l.b = append(l.b, `{"stream":{`...)
+		for j, lb := range s.labels {
+			if j > 0 {
+				l.b = append(l.b, ',')
+			}
+			l.JSONString(lb.key)
+			l.b = append(l.b, ':')
+			l.JSONString(lb.value)
+		}
+		l.b = append(l.b, `},"values":[`...)
+		for j, m := range s.values {
+			if j > 0 {
+				l.b = append(l.b, ',')
+			}
+			l.b = append(l.b, `["`...)
+			l.b = strconv.AppendInt(l.b, m.time.UnixNano(), 10)
+			l.b = append(l.b, `",`...)
+			l.JSONBytes(m.line)
+			l.b = append(l.b, ']')
+		}
+		l.b = append(l.b, "]}"...)
+	}
+	l.b = append(l.b, "]}"...)
+}
+
+// encodeBulk 编码成 elasticsearch _bulk 的 NDJSON
+func (h *HTTP) encodeBulk(l *Log, batch []*httpMessage) {
+	for _, m := range batch {
+		l.b = append(l.b, `{"create":{"_index":`...)
+		l.JSONString(h.index)
+		l.b = append(l.b, "}}\n"...)
+		h.encodeDoc(l, m)
+		l.b = append(l.b, '\n')
+	}
+}
+
+// encodeJSON 编码成 JSON 数组
+func (h *HTTP) encodeJSON(l *Log, batch []*httpMessage) {
+	l.b = append(l.b, '[')
+	for i, m := range batch {
+		if i > 0 {
+			l.b = append(l.b, ',')
+		}
+		h.encodeDoc(l, m)
+	}
+	l.b = append(l.b, ']')
+}
+
+// encodeDoc 编码一行日志
+//
+//	{"@timestamp":"RFC3339Nano","message":"line","k":"v","trace":"id"}
+func (h *HTTP) encodeDoc(l *Log, m *httpMessage) {
+	l.b = append(l.b, `{"@timestamp":"`...)
+	l.b = m.time.AppendFormat(l.b, time.RFC3339Nano)
+	l.b = append(l.b, `","message":`...)
+	l.JSONBytes(m.line)
+	for _, lb := range h.labels(m) {
+		l.b = append(l.b, ',')
+		l.JSONString(lb.key)
+		l.b = append(l.b, ':')
+		l.JSONString(lb.value)
+	}
+	if m.trace != "" {
+		l.b = append(l.b, `,"trace":`...)
+		l.JSONString(m.trace)
+	}
+	l.b = append(l.b, '}')
+}
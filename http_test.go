@@ -0,0 +1,208 @@
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testHTTPServer 保存收到的请求
+type testHTTPServer struct {
+	*httptest.Server
+	lock   sync.Mutex
+	bodies []string
+	// 前几次返回的状态码
+	fail []int
+}
+
+func newTestHTTPServer(t *testing.T) *testHTTPServer {
+	s := new(testHTTPServer)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = zr
+		}
+		d, err := io.ReadAll(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if len(s.fail) > 0 {
+			w.WriteHeader(s.fail[0])
+			s.fail = s.fail[1:]
+			return
+		}
+		s.bodies = append(s.bodies, string(d))
+	}))
+	return s
+}
+
+func (s *testHTTPServer) Bodies() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func Test_HTTPLoki(t *testing.T) {
+	now := Now
+	defer func() { Now = now }()
+	Now = func() time.Time {
+		return time.Unix(1, 2)
+	}
+	s := newTestHTTPServer(t)
+	defer s.Close()
+	h, err := NewHTTP(&HTTPConfig{
+		URL:          s.URL,
+		Format:       "loki",
+		Labels:       []string{"name", "level"},
+		StaticLabels: map[string]string{"job": "app"},
+		Gzip:         true,
+		BatchCount:   3,
+		Linger:       10000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	lg := NewLogger(h, nil, "svc")
+	lg.Info("a")
+	lg.Error("b")
+	lg.Info("c")
+	// 行数达到了，不需要等待
+	for i := 0; i < 100 && len(s.Bodies()) < 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bodies := s.Bodies()
+	if len(bodies) != 1 ||
+		bodies[0] != `{"streams":[`+
+			`{"stream":{"job":"app","level":"info","name":"svc"},"values":[["1000000002","[svc] [I] a"],["1000000002","[svc] [I] c"]]},`+
+			`{"stream":{"job":"app","level":"error","name":"svc"},"values":[["1000000002","[svc] [E] b"]]}]}` {
+		t.Fatal(bodies)
+	}
+}
+
+func Test_HTTPRetry(t *testing.T) {
+	s := newTestHTTPServer(t)
+	defer s.Close()
+	// 两次失败之后成功
+	s.fail = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+	h, err := NewHTTP(&HTTPConfig{
+		URL:     s.URL,
+		Format:  "elasticsearch",
+		Index:   "app",
+		Backoff: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := NewLogger(h, nil, "")
+	lg.WarnTrace("t1", `a"b`)
+	h.Flush()
+	bodies := s.Bodies()
+	if len(bodies) != 1 {
+		t.Fatal(bodies)
+	}
+	lines := strings.Split(strings.TrimSuffix(bodies[0], "\n"), "\n")
+	if len(lines) != 2 || lines[0] != `{"create":{"_index":"app"}}` {
+		t.Fatal(lines)
+	}
+	var doc map[string]string
+	err = json.Unmarshal([]byte(lines[1]), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["message"] != `[W] [t1] a"b` || doc["trace"] != "t1" || doc["@timestamp"] == "" {
+		t.Fatal(doc)
+	}
+	// 其他的错误不重试
+	s.lock.Lock()
+	s.fail = []int{http.StatusBadRequest, http.StatusBadRequest}
+	s.lock.Unlock()
+	lg.Info("drop")
+	h.Flush()
+	if h.Dropped() != 1 {
+		t.FailNow()
+	}
+	s.lock.Lock()
+	fail := len(s.fail)
+	s.lock.Unlock()
+	if fail != 1 {
+		t.FailNow()
+	}
+	// 关闭之后
+	h.Close()
+	if _, err := h.Write([]byte("closed")); err != errHTTPClosed {
+		t.FailNow()
+	}
+}
+
+func Test_HTTPCloseRetry(t *testing.T) {
+	s := newTestHTTPServer(t)
+	defer s.Close()
+	s.fail = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	h, err := NewHTTP(&HTTPConfig{
+		URL:     s.URL,
+		Linger:  1,
+		Backoff: 20000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("a\n"))
+	fail := func() int {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return len(s.fail)
+	}
+	for i := 0; i < 100 && fail() == 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 不等待重试的时间，立即最后重试一次
+	start := time.Now()
+	h.Close()
+	if time.Since(start) > 5*time.Second || fail() != 1 || h.Dropped() != 1 {
+		t.Fatal(time.Since(start), fail(), h.Dropped())
+	}
+}
+
+func Test_HTTPJSON(t *testing.T) {
+	s := newTestHTTPServer(t)
+	defer s.Close()
+	h, err := NewHTTP(&HTTPConfig{
+		URL:    s.URL,
+		Labels: []string{"level"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("[D] a\n"))
+	h.Write([]byte("b\n"))
+	h.Close()
+	bodies := s.Bodies()
+	if len(bodies) != 1 {
+		t.Fatal(bodies)
+	}
+	var docs []map[string]string
+	err = json.Unmarshal([]byte(bodies[0]), &docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 ||
+		docs[0]["message"] != "[D] a" || docs[0]["level"] != "debug" ||
+		docs[1]["message"] != "b" || docs[1]["level"] != "info" {
+		t.Fatal(docs)
+	}
+}
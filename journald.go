@@ -0,0 +1,196 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+const (
+	// 默认的 journald socket
+	defaultJournaldPath = "/run/systemd/journal/socket"
+	// 默认的追踪字段
+	defaultJournaldTraceField = "TRACE_ID"
+)
+
+var (
+	errJournaldClosed = errors.New("journald has been closed")
+)
+
+// JournaldConfig 是 NewJournald 的参数。
+type JournaldConfig struct {
+	// socket 的路径，默认是 /run/systemd/journal/socket
+	Path string `json:"path" yaml:"path"`
+	// SYSLOG_IDENTIFIER 字段，默认是 AppName
+	Identifier string `json:"identifier" yaml:"identifier"`
+	// 追踪的字段名，只能是大写字母，数字和下划线，默认是 TRACE_ID
+	TraceField string `json:"traceField" yaml:"traceField"`
+	// 名称的字段名，空不写入
+	NameField string `json:"nameField" yaml:"nameField"`
+	// 是否不写入 CODE_FILE ，CODE_LINE 和 CODE_FUNC
+	NoCaller bool `json:"noCaller" yaml:"noCaller"`
+}
+
+// NewJournald 返回一个 Journald 实例。
+func NewJournald(conf *JournaldConfig) (*Journald, error) {
+	j := new(Journald)
+	path := conf.Path
+	if path == "" {
+		path = defaultJournaldPath
+	}
+	j.addr = &net.UnixAddr{Name: path, Net: "unixgram"}
+	j.identifier = conf.Identifier
+	if j.identifier == "" {
+		j.identifier = AppName
+	}
+	j.traceField = conf.TraceField
+	if j.traceField == "" {
+		j.traceField = defaultJournaldTraceField
+	}
+	if !journaldFieldName(j.traceField) {
+		return nil, errors.New("journald trace field " + strconv.Quote(j.traceField) + " is invalid")
+	}
+	j.nameField = conf.NameField
+	if j.nameField != "" && !journaldFieldName(j.nameField) {
+		return nil, errors.New("journald name field " + strconv.Quote(j.nameField) + " is invalid")
+	}
+	j.caller = !conf.NoCaller
+	// 不连接，journald 重启之后也可以发送
+	var err error
+	j.conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// journaldFieldName 返回 name 是否是合法的字段名
+func journaldFieldName(name string) bool {
+	if name == "" || name[0] == '_' || len(name) > 64 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return name[0] < '0' || name[0] > '9'
+}
+
+// Journald 实现了 io.Writer 和 EntryWriter 接口，使用原生协议发送到 systemd-journald 。
+// 每一行日志是一条记录，MESSAGE 是编码后的一行，PRIORITY 是级别对应的 syslog 严重性，
+// WriteEntry 使用 Entry 的级别，调用者和追踪，写入 CODE_FILE ，CODE_LINE ，CODE_FUNC 和追踪字段，
+// Write 从 "[name] [level] " 前缀中解析级别。
+// 超过数据报大小的记录写入 memfd （不支持的时候是 /dev/shm 中删除了的临时文件），然后发送文件描述符。
+type Journald struct {
+	lock sync.Mutex
+	// 是否已关闭标志
+	closed bool
+	// 没有连接的 socket
+	conn *net.UnixConn
+	// journald 的地址
+	addr *net.UnixAddr
+	// 字段
+	identifier string
+	traceField string
+	nameField  string
+	// 是否写入调用者
+	caller bool
+	// 编码缓存
+	buf []byte
+}
+
+// Write 实现 io.Writer 。
+func (j *Journald) Write(b []byte) (int, error) {
+	return j.write(syslogLevel(b), "", "", nil, b)
+}
+
+// WriteEntry 实现 EntryWriter 。
+func (j *Journald) WriteEntry(e *Entry, b []byte) (int, error) {
+	var f *Frame
+	if j.caller {
+		f = pcFrame(e.CallerPC())
+	}
+	return j.write(e.Level, e.Name, e.Trace, f, b)
+}
+
+// write 编码记录，然后发送
+func (j *Journald) write(level Level, name, trace string, f *Frame, b []byte) (int, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closed {
+		return 0, errJournaldClosed
+	}
+	// 去掉换行
+	msg := b
+	for len(msg) > 0 && (msg[len(msg)-1] == '\n' || msg[len(msg)-1] == '\r') {
+		msg = msg[:len(msg)-1]
+	}
+	severity := 6
+	if level >= 0 && int(level) < len(syslogSeverities) {
+		severity = syslogSeverities[level]
+	}
+	d := j.buf[:0]
+	d = appendJournaldField(d, "MESSAGE", msg)
+	d = append(d, "PRIORITY="...)
+	d = strconv.AppendInt(d, int64(severity), 10)
+	d = append(d, '\n')
+	d = appendJournaldField(d, "SYSLOG_IDENTIFIER", []byte(j.identifier))
+	if f != nil {
+		d = appendJournaldField(d, "CODE_FILE", []byte(f.File))
+		d = append(d, "CODE_LINE="...)
+		d = strconv.AppendInt(d, int64(f.Line), 10)
+		d = append(d, '\n')
+		d = appendJournaldField(d, "CODE_FUNC", []byte(f.Function))
+	}
+	if trace != "" {
+		d = appendJournaldField(d, j.traceField, []byte(trace))
+	}
+	if name != "" && j.nameField != "" {
+		d = appendJournaldField(d, j.nameField, []byte(name))
+	}
+	j.buf = d
+	_, _, err := j.conn.WriteMsgUnix(d, nil, j.addr)
+	if err != nil {
+		// 太大了，使用文件描述符
+		if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+			return 0, err
+		}
+		err = journaldSendFile(j.conn, j.addr, d)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// appendJournaldField 写入一个字段，值有换行的时候使用长度前缀的格式
+func appendJournaldField(b []byte, name string, value []byte) []byte {
+	b = append(b, name...)
+	for _, c := range value {
+		if c == '\n' {
+			b = append(b, '\n')
+			b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+			b = append(b, value...)
+			return append(b, '\n')
+		}
+	}
+	b = append(b, '=')
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// Close 实现 io.Closer 接口，关闭 socket 。
+func (j *Journald) Close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closed {
+		return errJournaldClosed
+	}
+	j.closed = true
+	return j.conn.Close()
+}
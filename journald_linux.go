@@ -0,0 +1,73 @@
+package log
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// memfd_create 和 fcntl 的参数，fileSeals 是 F_SEAL_SEAL/SHRINK/GROW/WRITE
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+	fcntlAddSeals   = 1033
+	fileSeals       = 0x1 | 0x2 | 0x4 | 0x8
+)
+
+// memfdCreateTrap 是 memfd_create 的系统调用号，syscall 包中只有部分架构有
+var memfdCreateTrap = map[string]uintptr{
+	"386":      356,
+	"amd64":    319,
+	"arm":      385,
+	"arm64":    279,
+	"loong64":  279,
+	"riscv64":  279,
+	"mips":     4354,
+	"mipsle":   4354,
+	"mips64":   5314,
+	"mips64le": 5314,
+	"ppc64":    360,
+	"ppc64le":  360,
+	"s390x":    350,
+}
+
+// journaldSendFile 将记录写入 memfd ，然后把文件描述符发送给 journald
+func journaldSendFile(conn *net.UnixConn, addr *net.UnixAddr, b []byte) error {
+	f, sealed, err := journaldFile()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(b)
+	if err != nil {
+		return err
+	}
+	// journald 要求 memfd 是封闭的
+	if sealed {
+		_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), fcntlAddSeals, fileSeals)
+		if errno != 0 {
+			return os.NewSyscallError("fcntl", errno)
+		}
+	}
+	_, _, err = conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), addr)
+	return err
+}
+
+// journaldFile 创建 memfd ，不支持的时候在 /dev/shm 中创建临时文件然后删除
+func journaldFile() (*os.File, bool, error) {
+	if trap, ok := memfdCreateTrap[runtime.GOARCH]; ok {
+		name := []byte("journal-log\x00")
+		fd, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(&name[0])), mfdCloexec|mfdAllowSealing, 0)
+		if errno == 0 {
+			return os.NewFile(fd, "memfd:journal-log"), true, nil
+		}
+	}
+	f, err := os.CreateTemp("/dev/shm", "journal-log-")
+	if err != nil {
+		return nil, false, err
+	}
+	os.Remove(f.Name())
+	return f, false, nil
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// parseJournald 解析原生协议的记录
+func parseJournald(t *testing.T, b []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			t.Fatal(string(b))
+		}
+		line := b[:i]
+		b = b[i+1:]
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			continue
+		}
+		// 长度前缀
+		n := binary.LittleEndian.Uint64(b)
+		fields[string(line)] = string(b[8 : 8+n])
+		b = b[8+n+1:]
+	}
+	return fields
+}
+
+func Test_Journald(t *testing.T) {
+	// unix socket 的路径不能太长
+	dir, err := os.MkdirTemp("", "journald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	j, err := NewJournald(&JournaldConfig{Path: path, Identifier: "app", NameField: "LOGGER"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	read := func() map[string]string {
+		buf := make([]byte, 1024)
+		oob := make([]byte, 64)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oobn == 0 {
+			return parseJournald(t, buf[:n])
+		}
+		// 文件描述符
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			t.Fatal(err)
+		}
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := os.NewFile(uintptr(fds[0]), "journal")
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := make([]byte, info.Size())
+		_, err = f.ReadAt(d, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 封闭的 memfd 不能写入
+		if _, err = f.WriteAt([]byte("x"), 0); err == nil {
+			t.Fatal("memfd is not sealed")
+		}
+		return parseJournald(t, d)
+	}
+	lg := NewLogger(j, nil, "svc")
+	line := nextLine()
+	lg.WarnTrace("abc", "hello\nworld")
+	m := read()
+	if m["MESSAGE"] != "[svc] [W] [abc] hello\nworld" ||
+		m["PRIORITY"] != "4" ||
+		m["SYSLOG_IDENTIFIER"] != "app" ||
+		m["TRACE_ID"] != "abc" ||
+		m["LOGGER"] != "svc" ||
+		!strings.HasSuffix(m["CODE_FILE"], "journald_linux_test.go") ||
+		m["CODE_LINE"] != strconv.Itoa(line) ||
+		!strings.HasSuffix(m["CODE_FUNC"], ".Test_Journald") {
+		t.Fatal(m)
+	}
+	// 从前缀解析级别
+	j.Write([]byte("[E] plain\n"))
+	m = read()
+	if m["MESSAGE"] != "[E] plain" || m["PRIORITY"] != "3" || m["CODE_FILE"] != "" {
+		t.Fatal(m)
+	}
+	// 太大的使用文件描述符
+	large := strings.Repeat("a", 1<<20)
+	lg.Info(large)
+	m = read()
+	if m["MESSAGE"] != "[svc] [I] "+large || m["PRIORITY"] != "6" {
+		t.Fatal(len(m["MESSAGE"]))
+	}
+	j.Close()
+	if _, err := j.Write([]byte("closed")); err != errJournaldClosed {
+		t.FailNow()
+	}
+}
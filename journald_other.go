@@ -0,0 +1,13 @@
+//go:build !linux
+
+package log
+
+import (
+	"errors"
+	"net"
+)
+
+// journaldSendFile 只有 linux 支持
+func journaldSendFile(conn *net.UnixConn, addr *net.UnixAddr, b []byte) error {
+	return errors.New("journald: entry is too large")
+}
@@ -0,0 +1,21 @@
+package log
+
+import "testing"
+
+func Test_JournaldField(t *testing.T) {
+	for _, c := range []struct {
+		s  string
+		ok bool
+	}{
+		{"TRACE_ID", true},
+		{"A1", true},
+		{"", false},
+		{"_TRACE", false},
+		{"1A", false},
+		{"trace", false},
+	} {
+		if journaldFieldName(c.s) != c.ok {
+			t.Fatal(c.s)
+		}
+	}
+}
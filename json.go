@@ -0,0 +1,260 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// 十六进制
+	hexByte = "0123456789abcdef"
+)
+
+// JSONEncoder 每一行输出一个 JSON 对象
+// {"time":"","level":"","logger":"","trace":"","caller":"","msg":"",fields...}
+// logger 和 trace 为空不输出
+type JSONEncoder struct {
+	// 是否输出 caller
+	Caller bool
+	// caller 是否使用完整路径，否则只有文件名
+	FullPath bool
+	// 时间的格式，nil 使用 RFC3339Nano
+	Time *TimeFormatter
+}
+
+// Encode 实现 Encoder
+func (enc *JSONEncoder) Encode(l *Log, e *Entry) {
+	// 时间
+	l.b = append(l.b, `{"time":`...)
+	if enc.Time == nil {
+		l.b = append(l.b, '"')
+		l.b = Now().AppendFormat(l.b, time.RFC3339Nano)
+		l.b = append(l.b, '"')
+	} else if enc.Time.epoch() {
+		enc.Time.Format(l)
+	} else if enc.Time.layout == timeLayoutCustom {
+		// 自定义的格式可能有需要转义的字符
+		t := logPool.Get().(*Log)
+		t.b = t.b[:0]
+		enc.Time.Format(t)
+		l.JSONBytes(t.b)
+		logPool.Put(t)
+	} else {
+		l.b = append(l.b, '"')
+		enc.Time.Format(l)
+		l.b = append(l.b, '"')
+	}
+	// 级别
+	l.b = append(l.b, `,"level":"`...)
+	l.b = append(l.b, e.Level.String()...)
+	l.b = append(l.b, '"')
+	// 名称
+	if e.Name != "" {
+		l.b = append(l.b, `,"logger":`...)
+		l.JSONString(e.Name)
+	}
+	// 追踪
+	if e.Trace != "" {
+		l.b = append(l.b, `,"trace":`...)
+		l.JSONString(e.Trace)
+	}
+	// 调用
+	if enc.Caller {
+		path, line, ok := e.Caller()
+		if !ok {
+			path = "???"
+			line = -1
+		} else if !enc.FullPath {
+			path = trimPath(path)
+		}
+		l.b = append(l.b, `,"caller":"`...)
+		l.jsonEscape(path)
+		l.b = append(l.b, ':')
+		l.Int(line)
+		l.b = append(l.b, '"')
+	}
+	// 日志
+	l.b = append(l.b, `,"msg":`...)
+	l.JSONBytes(e.Message)
+	// 字段
+	if e.BoundEncoded != nil {
+		l.b = append(l.b, e.BoundEncoded...)
+	} else {
+		l.JSONFields(e.Bound)
+	}
+	l.JSONFields(e.Fields)
+	// 结束
+	l.b = append(l.b, '}', '\n')
+}
+
+// EncodeFields 实现 FieldsEncoder
+func (enc *JSONEncoder) EncodeFields(l *Log, fields []Field) {
+	l.JSONFields(fields)
+}
+
+// JSONFields 写入多个 ,"key":value
+func (l *Log) JSONFields(fields []Field) {
+	for i := 0; i < len(fields); i++ {
+		l.b = append(l.b, ',')
+		l.JSONString(fields[i].Key)
+		l.b = append(l.b, ':')
+		l.JSONFieldValue(&fields[i])
+	}
+}
+
+// JSONFieldValue 以 JSON 格式写入字段的值
+func (l *Log) JSONFieldValue(f *Field) {
+	switch f.Type {
+	case StringType:
+		l.JSONString(f.Str)
+	case IntType:
+		l.Int64(f.Int)
+	case UintType:
+		l.Uint64(uint64(f.Int))
+	case FloatType:
+		v := math.Float64frombits(uint64(f.Int))
+		// JSON 不支持
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			l.b = append(l.b, '"')
+			l.Float64(v)
+			l.b = append(l.b, '"')
+			return
+		}
+		l.Float64(v)
+	case BoolType:
+		l.Bool(f.Int == 1)
+	case DurationType:
+		l.b = append(l.b, '"')
+		l.Duration(time.Duration(f.Int))
+		l.b = append(l.b, '"')
+	case TimeType:
+		l.b = append(l.b, '"')
+		l.Time(f.TimeValue(), fieldTimeLayout)
+		l.b = append(l.b, '"')
+	case ErrorType:
+		if f.Any == nil {
+			l.b = append(l.b, "null"...)
+			return
+		}
+		l.JSONString(f.Any.(error).Error())
+	default:
+		d, err := json.Marshal(f.Any)
+		if err != nil {
+			l.JSONString(fmt.Sprint(f.Any))
+			return
+		}
+		l.b = append(l.b, d...)
+	}
+}
+
+// JSONString 写入带双引号的 JSON 字符串
+func (l *Log) JSONString(s string) {
+	l.b = append(l.b, '"')
+	l.jsonEscape(s)
+	l.b = append(l.b, '"')
+}
+
+// JSONBytes 写入带双引号的 JSON 字符串
+func (l *Log) JSONBytes(s []byte) {
+	l.b = append(l.b, '"')
+	l.jsonEscapeBytes(s)
+	l.b = append(l.b, '"')
+}
+
+// jsonEscape 写入转义后的字符串
+func (l *Log) jsonEscape(s string) {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			l.b = append(l.b, s[start:i]...)
+			l.jsonEscapeByte(c)
+			i++
+			start = i
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && n == 1 {
+			// 无效的 utf8
+			l.b = append(l.b, s[start:i]...)
+			l.b = append(l.b, "\ufffd"...)
+			i++
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			// js 的换行
+			l.b = append(l.b, s[start:i]...)
+			l.b = append(l.b, `\u202`...)
+			l.b = append(l.b, hexByte[r&0xf])
+			i += n
+			start = i
+			continue
+		}
+		i += n
+	}
+	l.b = append(l.b, s[start:]...)
+}
+
+// jsonEscapeBytes 写入转义后的字符串
+func (l *Log) jsonEscapeBytes(s []byte) {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			l.b = append(l.b, s[start:i]...)
+			l.jsonEscapeByte(c)
+			i++
+			start = i
+			continue
+		}
+		r, n := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && n == 1 {
+			// 无效的 utf8
+			l.b = append(l.b, s[start:i]...)
+			l.b = append(l.b, "\ufffd"...)
+			i++
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			// js 的换行
+			l.b = append(l.b, s[start:i]...)
+			l.b = append(l.b, `\u202`...)
+			l.b = append(l.b, hexByte[r&0xf])
+			i += n
+			start = i
+			continue
+		}
+		i += n
+	}
+	l.b = append(l.b, s[start:]...)
+}
+
+// jsonEscapeByte 写入转义后的 ascii 字符
+func (l *Log) jsonEscapeByte(c byte) {
+	switch c {
+	case '"', '\\':
+		l.b = append(l.b, '\\', c)
+	case '\n':
+		l.b = append(l.b, '\\', 'n')
+	case '\r':
+		l.b = append(l.b, '\\', 'r')
+	case '\t':
+		l.b = append(l.b, '\\', 't')
+	default:
+		l.b = append(l.b, `\u00`...)
+		l.b = append(l.b, hexByte[c>>4], hexByte[c&0xf])
+	}
+}
@@ -0,0 +1,94 @@
+package log
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func Test_JSONString(t *testing.T) {
+	l := &Log{}
+	for _, s := range []string{
+		"abc",
+		"a\"b\\c",
+		"a\nb\tc\r\x01",
+		"中文",
+		"  ",
+		"\xff",
+	} {
+		l.Reset()
+		l.JSONString(s)
+		var v string
+		if err := json.Unmarshal(l.b, &v); err != nil {
+			t.Fatal(err, string(l.b))
+		}
+		if s == "\xff" {
+			s = "�"
+		}
+		if v != s {
+			t.Fatalf("%q != %q", v, s)
+		}
+	}
+}
+
+func Test_JSONEncoder(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "json")
+	lg.Encoder = &JSONEncoder{Caller: true}
+	lg.InfoFieldsTrace("trace", "hello \"world\"",
+		String("s", "v"),
+		Int("i", 1),
+		Float64("nan", math.NaN()),
+		Any("m", map[string]int{"a": 1}),
+	)
+	var m map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &m); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if m["level"] != "info" ||
+		m["logger"] != "json" ||
+		m["trace"] != "trace" ||
+		m["msg"] != "hello \"world\"" ||
+		m["s"] != "v" ||
+		m["i"] != float64(1) ||
+		m["nan"] != "NaN" ||
+		m["m"].(map[string]any)["a"] != float64(1) {
+		t.Fatal(buf.String())
+	}
+	if !strings.HasPrefix(m["caller"].(string), "json_test.go:") {
+		t.Fatal(m["caller"])
+	}
+	// 没有名称和追踪
+	buf.Reset()
+	lg.Name = ""
+	lg.Errorf("%d", 1)
+	m = nil
+	if err := json.Unmarshal([]byte(buf.String()), &m); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if _, ok := m["logger"]; ok {
+		t.FailNow()
+	}
+	if _, ok := m["trace"]; ok {
+		t.FailNow()
+	}
+	if m["msg"] != "1" || m["level"] != "error" {
+		t.Fatal(buf.String())
+	}
+}
+
+func Benchmark_JSONEncoder(b *testing.B) {
+	lg := NewLogger(discard{}, DefaultHeader, "json")
+	lg.Encoder = &JSONEncoder{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lg.InfoFields("hello", String("s", "v"), Int("i", i))
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -1,2 +1,846 @@
 package log
 
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 协议
+	kafkaProduceKey      = 0
+	kafkaProduceVersion  = 3
+	kafkaMetadataKey     = 3
+	kafkaMetadataVersion = 1
+	// 默认的参数
+	defaultKafkaBatchSize = 1024 * 1024
+	defaultKafkaLinger    = 100 * time.Millisecond
+	defaultKafkaQueueSize = 10000
+	defaultKafkaRetry     = 3
+	defaultKafkaBackoff   = 100 * time.Millisecond
+	defaultKafkaTimeout   = 10 * time.Second
+	defaultKafkaClientID  = "log"
+)
+
+var (
+	errKafkaClosed      = errors.New("kafka has been closed")
+	errKafkaShortBuffer = errors.New("kafka short buffer")
+	errKafkaNoPartition = errors.New("kafka topic has no partition")
+	// RecordBatch 的 crc
+	crc32c = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// KafkaError 是 kafka 返回的错误码
+type KafkaError int16
+
+func (e KafkaError) Error() string {
+	return "kafka error code " + strconv.Itoa(int(e))
+}
+
+// KafkaConfig 是 NewKafka 的参数。
+type KafkaConfig struct {
+	// broker 的地址，host:port
+	Addrs []string `json:"addrs" yaml:"addrs" validate:"required,min=1"`
+	// 主题
+	Topic string `json:"topic" yaml:"topic" validate:"required"`
+	// 客户端标识，默认是 log
+	ClientID string `json:"clientID" yaml:"clientID"`
+	// 分区的键，name 使用 Logger 的名称，trace 使用追踪，空则轮流使用分区
+	Key string `json:"key" yaml:"key" validate:"omitempty,oneof=name trace"`
+	// 确认，none/leader/all ，默认是 leader
+	Acks string `json:"acks" yaml:"acks" validate:"omitempty,oneof=none leader all"`
+	// 每一个批次的最大字节，使用 1.5/K/M/G/T 这样的字符表示，默认是 1M
+	BatchSize string `json:"batchSize" yaml:"batchSize"`
+	// 批次等待的时间，单位毫秒，默认是 100
+	Linger int `json:"linger" yaml:"linger" validate:"omitempty,min=1"`
+	// 内存队列的最大行数，默认是 10000
+	QueueSize int `json:"queueSize" yaml:"queueSize" validate:"omitempty,min=1"`
+	// 队列满的时候丢弃 newest/oldest ，默认是 newest
+	Drop string `json:"drop" yaml:"drop" validate:"omitempty,oneof=newest oldest"`
+	// 发送失败的重试次数，默认是 3 ，小于 0 不重试
+	Retry int `json:"retry" yaml:"retry"`
+	// 第一次重试的等待时间，单位毫秒，之后每次翻倍，默认是 100
+	Backoff int `json:"backoff" yaml:"backoff" validate:"omitempty,min=1"`
+	// 网络读写的超时，单位毫秒，默认是 10000
+	Timeout int `json:"timeout" yaml:"timeout" validate:"omitempty,min=1"`
+}
+
+// kafkaMessage 是队列中的一行日志
+type kafkaMessage struct {
+	key   []byte
+	value []byte
+	time  int64
+}
+
+// kafkaMeta 是主题的元数据
+type kafkaMeta struct {
+	// broker id -> host:port
+	brokers map[int32]string
+	// 分区
+	partitions []int32
+	// 分区 -> leader id
+	leaders map[int32]int32
+}
+
+// NewKafka 返回一个 Kafka 实例。
+func NewKafka(conf *KafkaConfig) (*Kafka, error) {
+	if len(conf.Addrs) < 1 {
+		return nil, errors.New("kafka addrs is empty")
+	}
+	if conf.Topic == "" {
+		return nil, errors.New("kafka topic is empty")
+	}
+	k := new(Kafka)
+	k.addrs = conf.Addrs
+	k.topic = conf.Topic
+	k.clientID = conf.ClientID
+	if k.clientID == "" {
+		k.clientID = defaultKafkaClientID
+	}
+	k.key = conf.Key
+	switch conf.Acks {
+	case "none":
+		k.acks = 0
+	case "all":
+		k.acks = -1
+	default:
+		k.acks = 1
+	}
+	// 批次
+	k.batchSize = defaultKafkaBatchSize
+	if conf.BatchSize != "" {
+		size, err := ParseSize(conf.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if size > 0 {
+			k.batchSize = int(size)
+		}
+	}
+	k.linger = time.Duration(conf.Linger) * time.Millisecond
+	if k.linger <= 0 {
+		k.linger = defaultKafkaLinger
+	}
+	// 队列
+	queueSize := conf.QueueSize
+	if queueSize < 1 {
+		queueSize = defaultKafkaQueueSize
+	}
+	k.queue = make(chan *kafkaMessage, queueSize)
+	k.dropOldest = conf.Drop == "oldest"
+	// 重试
+	k.retry = conf.Retry
+	if k.retry == 0 {
+		k.retry = defaultKafkaRetry
+	} else if k.retry < 0 {
+		k.retry = 0
+	}
+	k.backoff = time.Duration(conf.Backoff) * time.Millisecond
+	if k.backoff <= 0 {
+		k.backoff = defaultKafkaBackoff
+	}
+	k.timeout = time.Duration(conf.Timeout) * time.Millisecond
+	if k.timeout <= 0 {
+		k.timeout = defaultKafkaTimeout
+	}
+	k.conns = make(map[int32]net.Conn)
+	k.exit = make(chan struct{})
+	k.flushReq = make(chan chan struct{})
+	// 启动发送协程
+	k.wait.Add(1)
+	go k.sendLoop()
+	return k, nil
+}
+
+// Kafka 实现了 io.Writer 和 EntryWriter 接口，可以作为 Logger 的输出。
+// 日志先进入内存队列，后台协程按照字节数和等待时间组成批次，发送到 kafka 。
+// 队列满的时候会丢弃日志，发送失败会重试，重试失败也会丢弃日志。
+type Kafka struct {
+	lock sync.Mutex
+	wait sync.WaitGroup
+	// 退出协程通知
+	exit chan struct{}
+	// Flush 请求
+	flushReq chan chan struct{}
+	// 是否已关闭标志
+	closed bool
+	// 队列
+	queue chan *kafkaMessage
+	// 队列满的时候是否丢弃最旧的
+	dropOldest bool
+	// 丢弃的行数
+	dropped atomic.Uint64
+	// 参数
+	addrs     []string
+	topic     string
+	clientID  string
+	key       string
+	acks      int16
+	batchSize int
+	linger    time.Duration
+	retry     int
+	backoff   time.Duration
+	timeout   time.Duration
+	// 以下只在发送协程使用
+	// 元数据
+	meta *kafkaMeta
+	// broker id -> 连接
+	conns map[int32]net.Conn
+	// 请求的序号
+	correlationID int32
+	// 没有键的时候使用的分区
+	next int
+	// 编码缓存
+	buf []byte
+}
+
+// Write 实现 io.Writer ，没有分区的键。
+func (k *Kafka) Write(b []byte) (int, error) {
+	return k.write(nil, b)
+}
+
+// WriteEntry 实现 EntryWriter ，按照配置使用 Logger 的名称或追踪作为键。
+func (k *Kafka) WriteEntry(e *Entry, b []byte) (int, error) {
+	var key string
+	switch k.key {
+	case "name":
+		key = e.Name
+	case "trace":
+		key = e.Trace
+	}
+	if key == "" {
+		return k.write(nil, b)
+	}
+	return k.write([]byte(key), b)
+}
+
+// Dropped 返回丢弃的行数
+func (k *Kafka) Dropped() uint64 {
+	return k.dropped.Load()
+}
+
+// write 复制数据，添加到队列
+func (k *Kafka) write(key, b []byte) (int, error) {
+	n := len(b)
+	// 去掉换行
+	if n > 0 && b[n-1] == '\n' {
+		b = b[:n-1]
+	}
+	m := &kafkaMessage{
+		key:   key,
+		value: append([]byte(nil), b...),
+		time:  time.Now().UnixMilli(),
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	// 关闭了
+	if k.closed {
+		return 0, errKafkaClosed
+	}
+	select {
+	case k.queue <- m:
+		return n, nil
+	default:
+	}
+	// 队列满了
+	k.dropped.Add(1)
+	if !k.dropOldest {
+		return n, nil
+	}
+	// 丢弃最旧的
+	select {
+	case <-k.queue:
+	default:
+	}
+	select {
+	case k.queue <- m:
+	default:
+	}
+	return n, nil
+}
+
+// Flush 实现 Flusher 接口，立即发送队列中的数据，等待发送完成。
+func (k *Kafka) Flush() error {
+	done := make(chan struct{})
+	k.lock.Lock()
+	if k.closed {
+		k.lock.Unlock()
+		return errKafkaClosed
+	}
+	k.lock.Unlock()
+	select {
+	case k.flushReq <- done:
+		<-done
+		return nil
+	case <-k.exit:
+		return errKafkaClosed
+	}
+}
+
+// Close 实现 io.Closer 接口，发送队列中的数据，等待协程退出。
+func (k *Kafka) Close() error {
+	k.lock.Lock()
+	if k.closed {
+		k.lock.Unlock()
+		return errKafkaClosed
+	}
+	k.closed = true
+	k.lock.Unlock()
+	// 结束协程通知。
+	close(k.exit)
+	// 等待退出。
+	k.wait.Wait()
+	return nil
+}
+
+// sendLoop 运行在一个协程中，组成批次发送。
+func (k *Kafka) sendLoop() {
+	lingerTimer := time.NewTimer(k.linger)
+	stopTimer(lingerTimer)
+	var batch []*kafkaMessage
+	size := 0
+	defer func() {
+		lingerTimer.Stop()
+		// 发送剩下的数据
+		k.flushAll(batch)
+		k.closeConns()
+		k.wait.Done()
+	}()
+	for {
+		select {
+		case m := <-k.queue:
+			// 第一条开始计时
+			if len(batch) < 1 {
+				lingerTimer.Reset(k.linger)
+			}
+			batch = append(batch, m)
+			size += len(m.key) + len(m.value)
+			// 达到最大字节，立即发送
+			if size >= k.batchSize {
+				stopTimer(lingerTimer)
+				k.flush(batch)
+				batch = batch[:0]
+				size = 0
+			}
+		case <-lingerTimer.C:
+			k.flush(batch)
+			batch = batch[:0]
+			size = 0
+		case done := <-k.flushReq:
+			stopTimer(lingerTimer)
+			k.flushAll(batch)
+			batch = batch[:0]
+			size = 0
+			close(done)
+		case <-k.exit:
+			return
+		}
+	}
+}
+
+// flushAll 发送 batch 和队列中所有的数据
+func (k *Kafka) flushAll(batch []*kafkaMessage) {
+	for {
+		select {
+		case m := <-k.queue:
+			batch = append(batch, m)
+			continue
+		default:
+		}
+		break
+	}
+	for len(batch) > 0 {
+		i := k.batchEnd(batch)
+		k.flush(batch[:i])
+		batch = batch[i:]
+	}
+}
+
+// batchEnd 返回不超过 batchSize 的批次的结束位置
+func (k *Kafka) batchEnd(batch []*kafkaMessage) int {
+	size := 0
+	for i, m := range batch {
+		size += len(m.key) + len(m.value)
+		if size >= k.batchSize {
+			return i + 1
+		}
+	}
+	return len(batch)
+}
+
+// flush 发送一个批次，失败的话重试，最后还是失败丢弃。
+// 退出的时候不再等待，立即最后重试一次。
+func (k *Kafka) flush(batch []*kafkaMessage) {
+	if len(batch) < 1 {
+		return
+	}
+	// 没有键的消息使用同一个分区
+	k.next++
+	backoff := k.backoff
+	exiting := false
+	for i := 0; ; i++ {
+		var err error
+		batch, err = k.produce(batch)
+		if err == nil {
+			return
+		}
+		// 重新获取元数据
+		k.meta = nil
+		if i >= k.retry || exiting {
+			k.dropped.Add(uint64(len(batch)))
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-k.exit:
+			timer.Stop()
+			exiting = true
+		}
+		backoff *= 2
+	}
+}
+
+// produce 按照分区的 leader 发送，返回失败的消息和最后一个错误。
+func (k *Kafka) produce(batch []*kafkaMessage) ([]*kafkaMessage, error) {
+	meta, err := k.metadata()
+	if err != nil {
+		return batch, err
+	}
+	// 分区
+	partitions := make(map[int32][]*kafkaMessage)
+	for _, m := range batch {
+		p := k.partition(meta, m.key)
+		partitions[p] = append(partitions[p], m)
+	}
+	// leader
+	leaders := make(map[int32][]int32)
+	for p := range partitions {
+		id := meta.leaders[p]
+		leaders[id] = append(leaders[id], p)
+	}
+	// 发送
+	var failed []*kafkaMessage
+	for id, ps := range leaders {
+		var fps []int32
+		fps, err = k.send(meta, id, ps, partitions)
+		for _, p := range fps {
+			failed = append(failed, partitions[p]...)
+		}
+	}
+	if len(failed) > 0 {
+		return failed, err
+	}
+	return nil, nil
+}
+
+// partition 返回键对应的分区
+func (k *Kafka) partition(meta *kafkaMeta, key []byte) int32 {
+	n := len(meta.partitions)
+	if key == nil {
+		return meta.partitions[k.next%n]
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return meta.partitions[int(h.Sum32()%uint32(n))]
+}
+
+// metadata 返回主题的元数据，没有的话从 broker 获取。
+func (k *Kafka) metadata() (*kafkaMeta, error) {
+	if k.meta != nil {
+		return k.meta, nil
+	}
+	var err error
+	for _, addr := range k.addrs {
+		k.meta, err = k.requestMetadata(addr)
+		if err == nil {
+			return k.meta, nil
+		}
+	}
+	return nil, err
+}
+
+// requestMetadata 向 addr 请求元数据
+func (k *Kafka) requestMetadata(addr string) (*kafkaMeta, error) {
+	conn, err := net.DialTimeout("tcp", addr, k.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// 请求
+	b := k.appendRequestHeader(k.buf[:0], kafkaMetadataKey, kafkaMetadataVersion)
+	b = appendKafkaInt32(b, 1)
+	b = appendKafkaString(b, k.topic)
+	k.buf = b
+	res, err := k.roundTrip(conn, b)
+	if err != nil {
+		return nil, err
+	}
+	return parseKafkaMetadata(res, k.topic)
+}
+
+// send 发送分区的数据到 broker ，返回失败的分区和错误
+func (k *Kafka) send(meta *kafkaMeta, id int32, ps []int32, partitions map[int32][]*kafkaMessage) ([]int32, error) {
+	conn, err := k.conn(meta, id)
+	if err != nil {
+		return ps, err
+	}
+	// 请求
+	b := k.appendRequestHeader(k.buf[:0], kafkaProduceKey, kafkaProduceVersion)
+	// transactional_id
+	b = appendKafkaInt16(b, -1)
+	b = appendKafkaInt16(b, k.acks)
+	b = appendKafkaInt32(b, int32(k.timeout/time.Millisecond))
+	// topic_data
+	b = appendKafkaInt32(b, 1)
+	b = appendKafkaString(b, k.topic)
+	b = appendKafkaInt32(b, int32(len(ps)))
+	for _, p := range ps {
+		b = appendKafkaInt32(b, p)
+		// records
+		i := len(b)
+		b = appendKafkaInt32(b, 0)
+		b = appendKafkaRecordBatch(b, partitions[p])
+		binary.BigEndian.PutUint32(b[i:], uint32(len(b)-i-4))
+	}
+	k.buf = b
+	// 不需要确认
+	if k.acks == 0 {
+		err = k.writeRequest(conn, b)
+		if err != nil {
+			k.closeConn(id)
+			return ps, err
+		}
+		return nil, nil
+	}
+	res, err := k.roundTrip(conn, b)
+	if err != nil {
+		k.closeConn(id)
+		return ps, err
+	}
+	return parseKafkaProduce(res)
+}
+
+// conn 返回 broker 的连接，没有的话创建
+func (k *Kafka) conn(meta *kafkaMeta, id int32) (net.Conn, error) {
+	conn := k.conns[id]
+	if conn != nil {
+		return conn, nil
+	}
+	addr, ok := meta.brokers[id]
+	if !ok {
+		return nil, fmt.Errorf("kafka broker %d not found", id)
+	}
+	conn, err := net.DialTimeout("tcp", addr, k.timeout)
+	if err != nil {
+		return nil, err
+	}
+	k.conns[id] = conn
+	return conn, nil
+}
+
+// closeConn 关闭 broker 的连接
+func (k *Kafka) closeConn(id int32) {
+	conn := k.conns[id]
+	if conn != nil {
+		conn.Close()
+		delete(k.conns, id)
+	}
+}
+
+// closeConns 关闭所有的连接
+func (k *Kafka) closeConns() {
+	for id := range k.conns {
+		k.closeConn(id)
+	}
+}
+
+// appendRequestHeader 编码请求头，前 4 个字节是请求的长度
+func (k *Kafka) appendRequestHeader(b []byte, key, version int16) []byte {
+	k.correlationID++
+	b = appendKafkaInt32(b, 0)
+	b = appendKafkaInt16(b, key)
+	b = appendKafkaInt16(b, version)
+	b = appendKafkaInt32(b, k.correlationID)
+	return appendKafkaString(b, k.clientID)
+}
+
+// writeRequest 写入请求，b 的前 4 个字节设置为长度
+func (k *Kafka) writeRequest(conn net.Conn, b []byte) error {
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	conn.SetWriteDeadline(time.Now().Add(k.timeout))
+	_, err := conn.Write(b)
+	return err
+}
+
+// roundTrip 写入请求，返回响应去掉 correlation_id 的数据
+func (k *Kafka) roundTrip(conn net.Conn, b []byte) (*kafkaReader, error) {
+	err := k.writeRequest(conn, b)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(k.timeout))
+	res, err := readKafkaFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	r := &kafkaReader{b: res}
+	id := r.int32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if id != k.correlationID {
+		return nil, fmt.Errorf("kafka correlation id %d != %d", id, k.correlationID)
+	}
+	return r, nil
+}
+
+// readKafkaFrame 读取一个长度前缀的数据
+func readKafkaFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	_, err := io.ReadFull(r, size[:])
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint32(size[:]))
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// parseKafkaMetadata 解析 metadata v1 的响应
+func parseKafkaMetadata(r *kafkaReader, topic string) (*kafkaMeta, error) {
+	meta := new(kafkaMeta)
+	meta.brokers = make(map[int32]string)
+	meta.leaders = make(map[int32]int32)
+	// brokers
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		// rack
+		r.string()
+		meta.brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	// controller_id
+	r.int32()
+	// topics
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		code := r.int16()
+		name := r.string()
+		// is_internal
+		r.int8()
+		if name == topic && code != 0 {
+			return nil, KafkaError(code)
+		}
+		for m := r.int32(); m > 0 && r.err == nil; m-- {
+			code := r.int16()
+			p := r.int32()
+			leader := r.int32()
+			// replica_nodes
+			for i := r.int32(); i > 0 && r.err == nil; i-- {
+				r.int32()
+			}
+			// isr_nodes
+			for i := r.int32(); i > 0 && r.err == nil; i-- {
+				r.int32()
+			}
+			if name != topic || code != 0 || leader < 0 {
+				continue
+			}
+			meta.partitions = append(meta.partitions, p)
+			meta.leaders[p] = leader
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(meta.partitions) < 1 {
+		return nil, errKafkaNoPartition
+	}
+	return meta, nil
+}
+
+// parseKafkaProduce 解析 produce v3 的响应，返回失败的分区和错误
+func parseKafkaProduce(r *kafkaReader) ([]int32, error) {
+	var failed []int32
+	var err error
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		// name
+		r.string()
+		for m := r.int32(); m > 0 && r.err == nil; m-- {
+			p := r.int32()
+			code := r.int16()
+			// base_offset
+			r.int64()
+			// log_append_time_ms
+			r.int64()
+			if code != 0 {
+				failed = append(failed, p)
+				err = KafkaError(code)
+			}
+		}
+	}
+	if r.err != nil {
+		return failed, r.err
+	}
+	return failed, err
+}
+
+// appendKafkaRecordBatch 编码 RecordBatch v2
+func appendKafkaRecordBatch(b []byte, msgs []*kafkaMessage) []byte {
+	start := len(b)
+	// base_offset
+	b = appendKafkaInt64(b, 0)
+	// batch_length
+	b = appendKafkaInt32(b, 0)
+	// partition_leader_epoch
+	b = appendKafkaInt32(b, -1)
+	// magic
+	b = append(b, 2)
+	// crc
+	crcPos := len(b)
+	b = appendKafkaInt32(b, 0)
+	// attributes
+	b = appendKafkaInt16(b, 0)
+	// last_offset_delta
+	b = appendKafkaInt32(b, int32(len(msgs)-1))
+	// first_timestamp，max_timestamp ，多个协程写入的时候不一定是按时间排序的
+	first, max := msgs[0].time, msgs[0].time
+	for _, m := range msgs[1:] {
+		if m.time < first {
+			first = m.time
+		}
+		if m.time > max {
+			max = m.time
+		}
+	}
+	b = appendKafkaInt64(b, first)
+	b = appendKafkaInt64(b, max)
+	// producer_id，producer_epoch，base_sequence
+	b = appendKafkaInt64(b, -1)
+	b = appendKafkaInt16(b, -1)
+	b = appendKafkaInt32(b, -1)
+	// records
+	b = appendKafkaInt32(b, int32(len(msgs)))
+	for i, m := range msgs {
+		keyLen := int64(-1)
+		if m.key != nil {
+			keyLen = int64(len(m.key))
+		}
+		delta := m.time - first
+		size := 1 + varintLen(delta) + varintLen(int64(i)) +
+			varintLen(keyLen) + len(m.key) +
+			varintLen(int64(len(m.value))) + len(m.value) +
+			varintLen(0)
+		b = binary.AppendVarint(b, int64(size))
+		// attributes
+		b = append(b, 0)
+		b = binary.AppendVarint(b, delta)
+		b = binary.AppendVarint(b, int64(i))
+		b = binary.AppendVarint(b, keyLen)
+		b = append(b, m.key...)
+		b = binary.AppendVarint(b, int64(len(m.value)))
+		b = append(b, m.value...)
+		// headers
+		b = binary.AppendVarint(b, 0)
+	}
+	binary.BigEndian.PutUint32(b[start+8:], uint32(len(b)-start-12))
+	binary.BigEndian.PutUint32(b[crcPos:], crc32.Checksum(b[crcPos+4:], crc32c))
+	return b
+}
+
+// varintLen 返回 zigzag varint 编码的字节数
+func varintLen(v int64) int {
+	var b [binary.MaxVarintLen64]byte
+	return binary.PutVarint(b[:], v)
+}
+
+func appendKafkaInt16(b []byte, v int16) []byte {
+	return binary.BigEndian.AppendUint16(b, uint16(v))
+}
+
+func appendKafkaInt32(b []byte, v int32) []byte {
+	return binary.BigEndian.AppendUint32(b, uint32(v))
+}
+
+func appendKafkaInt64(b []byte, v int64) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(v))
+}
+
+func appendKafkaString(b []byte, s string) []byte {
+	b = appendKafkaInt16(b, int16(len(s)))
+	return append(b, s...)
+}
+
+// kafkaReader 用于解析 kafka 协议，出错之后读取的都是零值
+type kafkaReader struct {
+	b   []byte
+	err error
+}
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = errKafkaShortBuffer
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *kafkaReader) int8() int8 {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (r *kafkaReader) int16() int16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *kafkaReader) int32() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *kafkaReader) int64() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// string 读取 int16 长度的字符串，-1 是 null
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
@@ -0,0 +1,338 @@
+package log
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bytes 读取 int32 长度的数据，-1 是 null
+func (r *kafkaReader) bytes() []byte {
+	n := r.int32()
+	if n < 0 {
+		return nil
+	}
+	return r.next(int(n))
+}
+
+// varint 读取 zigzag varint
+func (r *kafkaReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.err = errKafkaShortBuffer
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// testKafkaRecord 是 fake broker 收到的消息
+type testKafkaRecord struct {
+	partition int32
+	key       string
+	value     string
+}
+
+// testKafkaBroker 是一个只支持 metadata v1 和 produce v3 的 broker
+type testKafkaBroker struct {
+	t          *testing.T
+	lis        net.Listener
+	partitions int32
+	lock       sync.Mutex
+	records    []testKafkaRecord
+	// 前几次 produce 返回的错误码
+	failProduce int
+}
+
+func newTestKafkaBroker(t *testing.T, partitions int32) *testKafkaBroker {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testKafkaBroker{t: t, lis: lis, partitions: partitions}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testKafkaBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		req, err := readKafkaFrame(conn)
+		if err != nil {
+			return
+		}
+		r := &kafkaReader{b: req}
+		key := r.int16()
+		version := r.int16()
+		id := r.int32()
+		r.string()
+		res := appendKafkaInt32(nil, 0)
+		res = appendKafkaInt32(res, id)
+		switch {
+		case key == kafkaMetadataKey && version == kafkaMetadataVersion:
+			res = b.metadata(r, res)
+		case key == kafkaProduceKey && version == kafkaProduceVersion:
+			var acks int16
+			res, acks = b.produce(r, res)
+			if acks == 0 {
+				continue
+			}
+		default:
+			b.t.Errorf("unknown request %d %d", key, version)
+			return
+		}
+		if r.err != nil {
+			b.t.Error(r.err)
+			return
+		}
+		binary.BigEndian.PutUint32(res, uint32(len(res)-4))
+		conn.Write(res)
+	}
+}
+
+func (b *testKafkaBroker) metadata(r *kafkaReader, res []byte) []byte {
+	var topics []string
+	for n := r.int32(); n > 0; n-- {
+		topics = append(topics, r.string())
+	}
+	host, port, _ := net.SplitHostPort(b.lis.Addr().String())
+	p, _ := strconv.Atoi(port)
+	// brokers
+	res = appendKafkaInt32(res, 1)
+	res = appendKafkaInt32(res, 1)
+	res = appendKafkaString(res, host)
+	res = appendKafkaInt32(res, int32(p))
+	res = appendKafkaInt16(res, -1)
+	// controller_id
+	res = appendKafkaInt32(res, 1)
+	// topics
+	res = appendKafkaInt32(res, int32(len(topics)))
+	for _, topic := range topics {
+		res = appendKafkaInt16(res, 0)
+		res = appendKafkaString(res, topic)
+		res = append(res, 0)
+		res = appendKafkaInt32(res, b.partitions)
+		for i := int32(0); i < b.partitions; i++ {
+			res = appendKafkaInt16(res, 0)
+			res = appendKafkaInt32(res, i)
+			res = appendKafkaInt32(res, 1)
+			res = appendKafkaInt32(res, 1)
+			res = appendKafkaInt32(res, 1)
+			res = appendKafkaInt32(res, 1)
+			res = appendKafkaInt32(res, 1)
+		}
+	}
+	return res
+}
+
+func (b *testKafkaBroker) produce(r *kafkaReader, res []byte) ([]byte, int16) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var code int16
+	if b.failProduce > 0 {
+		b.failProduce--
+		code = 6
+	}
+	// transactional_id
+	r.string()
+	acks := r.int16()
+	r.int32()
+	res = appendKafkaInt32(res, r.int32())
+	topic := r.string()
+	res = appendKafkaString(res, topic)
+	n := r.int32()
+	res = appendKafkaInt32(res, n)
+	for ; n > 0; n-- {
+		p := r.int32()
+		records := b.recordBatch(p, &kafkaReader{b: r.bytes()}, code == 0)
+		res = appendKafkaInt32(res, p)
+		res = appendKafkaInt16(res, code)
+		res = appendKafkaInt64(res, int64(records))
+		res = appendKafkaInt64(res, -1)
+	}
+	// throttle_time_ms
+	res = appendKafkaInt32(res, 0)
+	return res, acks
+}
+
+func (b *testKafkaBroker) recordBatch(p int32, r *kafkaReader, save bool) int {
+	r.int64()
+	if int(r.int32()) != len(r.b) {
+		b.t.Error("batch length")
+	}
+	r.int32()
+	if r.int8() != 2 {
+		b.t.Error("magic")
+	}
+	crc := uint32(r.int32())
+	if crc != crc32.Checksum(r.b, crc32c) {
+		b.t.Error("crc")
+	}
+	r.int16()
+	r.int32()
+	r.int64()
+	r.int64()
+	r.int64()
+	r.int16()
+	r.int32()
+	n := int(r.int32())
+	for i := 0; i < n; i++ {
+		size := r.varint()
+		rr := &kafkaReader{b: r.next(int(size))}
+		rr.int8()
+		rr.varint()
+		if rr.varint() != int64(i) {
+			b.t.Error("offset delta")
+		}
+		var key string
+		if kl := rr.varint(); kl >= 0 {
+			key = string(rr.next(int(kl)))
+		}
+		value := string(rr.next(int(rr.varint())))
+		if rr.varint() != 0 || len(rr.b) != 0 || rr.err != nil {
+			b.t.Error("record")
+		}
+		if save {
+			b.records = append(b.records, testKafkaRecord{partition: p, key: key, value: value})
+		}
+	}
+	return n
+}
+
+func (b *testKafkaBroker) Records() []testKafkaRecord {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]testKafkaRecord(nil), b.records...)
+}
+
+func Test_Kafka(t *testing.T) {
+	broker := newTestKafkaBroker(t, 3)
+	defer broker.lis.Close()
+	// 第一次失败，重试
+	broker.failProduce = 1
+	k, err := NewKafka(&KafkaConfig{
+		Addrs:   []string{broker.lis.Addr().String()},
+		Topic:   "logs",
+		Key:     "name",
+		Linger:  10,
+		Backoff: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := NewLogger(k, DefaultHeader, "kafka")
+	for i := 0; i < 10; i++ {
+		lg.Info(i)
+	}
+	k.Flush()
+	if len(broker.Records()) != 10 {
+		t.Fatal(broker.Records())
+	}
+	// Close 发送剩下的
+	lg.Info(10)
+	k.Close()
+	if _, err := k.Write([]byte("closed")); err != errKafkaClosed {
+		t.FailNow()
+	}
+	records := broker.Records()
+	if len(records) != 11 {
+		t.Fatal(records)
+	}
+	for i, r := range records {
+		if r.key != "kafka" ||
+			r.partition != records[0].partition ||
+			!strings.HasPrefix(r.value, "[kafka] [I] ") ||
+			!strings.HasSuffix(r.value, " "+strconv.Itoa(i)) {
+			t.Fatal(r)
+		}
+	}
+	if k.Dropped() != 0 {
+		t.FailNow()
+	}
+}
+
+func Test_KafkaCloseRetry(t *testing.T) {
+	broker := newTestKafkaBroker(t, 1)
+	defer broker.lis.Close()
+	broker.failProduce = 3
+	k, err := NewKafka(&KafkaConfig{
+		Addrs:   []string{broker.lis.Addr().String()},
+		Topic:   "logs",
+		Linger:  1,
+		Backoff: 20000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Write([]byte("a\n"))
+	fail := func() int {
+		broker.lock.Lock()
+		defer broker.lock.Unlock()
+		return broker.failProduce
+	}
+	for i := 0; i < 100 && fail() == 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 不等待重试的时间，立即最后重试一次
+	start := time.Now()
+	k.Close()
+	if time.Since(start) > 5*time.Second || fail() != 1 || k.Dropped() != 1 {
+		t.Fatal(time.Since(start), fail(), k.Dropped())
+	}
+}
+
+func Test_KafkaRecordBatchTimestamp(t *testing.T) {
+	b := appendKafkaRecordBatch(nil, []*kafkaMessage{
+		{value: []byte("a"), time: 5},
+		{value: []byte("b"), time: 3},
+		{value: []byte("c"), time: 7},
+	})
+	// 最小和最大的时间
+	r := &kafkaReader{b: b[27:]}
+	if first, max := r.int64(), r.int64(); first != 3 || max != 7 {
+		t.Fatal(first, max)
+	}
+	// 第一条消息的时间差，跳过 producer ，数量，长度和属性
+	r.next(14)
+	r.int32()
+	r.varint()
+	r.next(1)
+	if d := r.varint(); d != 2 {
+		t.Fatal(d)
+	}
+}
+
+func Test_KafkaDrop(t *testing.T) {
+	// 没有发送协程
+	k := &Kafka{queue: make(chan *kafkaMessage, 2)}
+	for i := 0; i < 3; i++ {
+		k.Write([]byte(strconv.Itoa(i) + "\n"))
+	}
+	if k.Dropped() != 1 || string((<-k.queue).value) != "0" {
+		t.FailNow()
+	}
+	// 丢弃最旧的
+	k = &Kafka{queue: make(chan *kafkaMessage, 2), dropOldest: true}
+	for i := 0; i < 3; i++ {
+		k.Write([]byte(strconv.Itoa(i) + "\n"))
+	}
+	if k.Dropped() != 1 || string((<-k.queue).value) != "1" {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Level 日志级别
+type Level int32
+
+// 级别
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	PanicLevel
+	FatalLevel
+)
+
+var (
+	// 级别的名称
+	levelNames = []string{"debug", "info", "warn", "error", "panic", "fatal"}
+)
+
+// String 返回级别的名称
+func (l Level) String() string {
+	if l >= DebugLevel && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// ParseLevel 解析 debug/info/warn/error/panic/fatal ，不区分大小写，
+// 也可以使用首字母 d/i/w/e/p/f 。
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug", "d":
+		return DebugLevel, nil
+	case "info", "i":
+		return InfoLevel, nil
+	case "warn", "warning", "w":
+		return WarnLevel, nil
+	case "error", "e":
+		return ErrorLevel, nil
+	case "panic", "p":
+		return PanicLevel, nil
+	case "fatal", "f":
+		return FatalLevel, nil
+	}
+	return DebugLevel, fmt.Errorf("unknown level %q", s)
+}
+
+// LevelVar 是可以并发读写的级别，零值是 DebugLevel
+type LevelVar struct {
+	v atomic.Int32
+}
+
+// Level 返回级别
+func (v *LevelVar) Level() Level {
+	return Level(v.v.Load())
+}
+
+// Set 设置级别
+func (v *LevelVar) Set(level Level) {
+	v.v.Store(int32(level))
+}
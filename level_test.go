@@ -0,0 +1,91 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_ParseLevel(t *testing.T) {
+	for _, c := range []struct {
+		s string
+		l Level
+	}{
+		{"debug", DebugLevel},
+		{"INFO", InfoLevel},
+		{"Warning", WarnLevel},
+		{"w", WarnLevel},
+		{" error ", ErrorLevel},
+		{"panic", PanicLevel},
+	} {
+		l, err := ParseLevel(c.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l != c.l {
+			t.Fatal(c.s)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.FailNow()
+	}
+	if WarnLevel.String() != "warn" {
+		t.FailNow()
+	}
+}
+
+func Test_LoggerLevel(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "")
+	lg.SetLevel(WarnLevel)
+	lg.Debug(1)
+	lg.Info(1)
+	if buf.Len() != 0 || lg.Level() != WarnLevel {
+		t.FailNow()
+	}
+	lg.Warn(1)
+	lg.Error(1)
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Fatal(buf.String())
+	}
+	// 兼容
+	buf.Reset()
+	lg.SetLevel(DebugLevel)
+	lg.DisableError = true
+	lg.Error(1)
+	if buf.Len() != 0 {
+		t.FailNow()
+	}
+	// 并发
+	lg = NewLogger(discard{}, DefaultHeader, "")
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			for j := 0; j < 100; j++ {
+				lg.SetLevel(Level(j % 4))
+				lg.Infof("%d", i)
+			}
+		}(i)
+	}
+	wait.Wait()
+	// 不是 NewLogger 创建的
+	zero := &Logger{Writer: discard{}}
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			for j := 0; j < 100; j++ {
+				zero.SetLevel(Level(j % 4))
+				zero.Named("n").With(Int("i", i)).Infof("%d", zero.Level())
+			}
+		}(i)
+	}
+	wait.Wait()
+	child := zero.Named("c")
+	zero.SetLevel(ErrorLevel)
+	if child.Level() != ErrorLevel {
+		t.FailNow()
+	}
+}
@@ -1,12 +1,21 @@
 package log
 
-import "sync"
+import (
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
 
 var (
 	// 用于格式化整数
 	intByte []byte
 	// 缓存池
 	logPool sync.Pool
+	// Entry 缓存池
+	entryPool sync.Pool
+	// 默认的编码
+	defaultEncoder Encoder = TextEncoder{}
 )
 
 func init() {
@@ -17,6 +26,9 @@ func init() {
 	logPool.New = func() any {
 		return new(Log)
 	}
+	entryPool.New = func() any {
+		return new(Entry)
+	}
 }
 
 // Log 用于写入一行日志
@@ -25,6 +37,12 @@ type Log struct {
 	b []byte
 	// 用于整数格式化
 	f []byte
+	// 调用者的 pc ，不为 0 时头格式使用它代替 depth
+	pc uintptr
+	// 正在编码的 Entry ，NewHeader 的模板使用
+	e *Entry
+	// NewHeader 的模板解析的调用者
+	frame *Frame
 }
 
 // Reset 重置缓存
@@ -125,3 +143,65 @@ func (l *Log) Bytes(s []byte) {
 func (l *Log) Byte(s byte) {
 	l.b = append(l.b, s)
 }
+
+// Int64 写入 int64 整数
+func (l *Log) Int64(v int64) {
+	l.b = strconv.AppendInt(l.b, v, 10)
+}
+
+// Uint64 写入 uint64 整数
+func (l *Log) Uint64(v uint64) {
+	l.b = strconv.AppendUint(l.b, v, 10)
+}
+
+// Float64 写入浮点数
+func (l *Log) Float64(v float64) {
+	l.b = strconv.AppendFloat(l.b, v, 'g', -1, 64)
+}
+
+// Bool 写入 true/false
+func (l *Log) Bool(v bool) {
+	l.b = strconv.AppendBool(l.b, v)
+}
+
+// Duration 写入时长，格式和 time.Duration.String 相同
+func (l *Log) Duration(v time.Duration) {
+	l.b = append(l.b, v.String()...)
+}
+
+// Time 使用 layout 写入时间
+func (l *Log) Time(v time.Time, layout string) {
+	l.b = v.AppendFormat(l.b, layout)
+}
+
+// Quote 写入字符串，如果包含空白，引号，等号或者不可见字符，使用双引号转义
+func (l *Log) Quote(s string) {
+	if !needQuote(s) {
+		l.b = append(l.b, s...)
+		return
+	}
+	l.b = strconv.AppendQuote(l.b, s)
+}
+
+// needQuote 判断字符串在文本格式中是否需要引号
+func needQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '"' || c == '=' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !strconv.IsPrint(r) {
+			return true
+		}
+		i += n
+	}
+	return false
+}
@@ -1,8 +1,10 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"reflect"
 	"runtime"
 )
 
@@ -10,22 +12,40 @@ const (
 	loggerDepth = 3
 )
 
-// Logger 默认实现，修改字段注意并发
+// Logger 默认实现，修改字段注意并发，级别使用 SetLevel 修改
 type Logger struct {
 	// 输出
 	io.Writer
 	// 头格式
 	Header FormatHeader
-	// 名称
+	// 编码，nil 使用 TextEncoder
+	Encoder Encoder
+	// XxxCtx 函数提取 context 的追踪和字段，nil 使用 DefaultContextExtractor
+	Extractor ContextExtractor
+	// 名称，不包括 "[]" ，由 Encoder 输出
 	Name string
 	// 是否禁止 debug
+	// Deprecated: 使用 SetLevel
 	DisableDebug bool
 	// 是否禁止 info
+	// Deprecated: 使用 SetLevel
 	DisableInfo bool
 	// 是否禁止 warn
+	// Deprecated: 使用 SetLevel
 	DisableWarn bool
 	// 是否禁止 error
+	// Deprecated: 使用 SetLevel
 	DisableError bool
+	// 最小级别，零值是 DebugLevel
+	level LevelVar
+	// 子 Logger 共享的级别，nil 使用 level
+	sharedLevel *LevelVar
+	// With 绑定的字段
+	bound []Field
+	// 预先编码的 bound
+	boundEncoded []byte
+	// 编码 bound 的 Encoder ，只保存可以比较的，否则比较的时候会 panic
+	boundEncoder Encoder
 }
 
 // NewLogger 返回默认的 Logger
@@ -34,109 +54,218 @@ func NewLogger(writer io.Writer, header FormatHeader, name string) *Logger {
 	lg := new(Logger)
 	lg.Writer = writer
 	lg.Header = header
-	// 多加一个空格
-	if name != "" {
-		lg.Name = fmt.Sprintf("[%s] ", name)
-	}
+	lg.Name = name
 	return lg
 }
 
-func (lg *Logger) print(depth, level int, args ...any) {
+// Named 返回名称是 "name.name" 的子 Logger ，共享输出，头格式和级别
+func (lg *Logger) Named(name string) *Logger {
+	c := lg.clone()
+	if lg.Name != "" && name != "" {
+		c.Name = lg.Name + "." + name
+	} else if name != "" {
+		c.Name = name
+	}
+	return c
+}
+
+// With 返回绑定了 fields 的子 Logger ，每一行日志都会输出这些字段，
+// 共享输出，头格式和级别。字段在这里使用当前的 Encoder 预先编码。
+func (lg *Logger) With(fields ...Field) *Logger {
+	c := lg.clone()
+	if len(fields) < 1 {
+		return c
+	}
+	c.bound = make([]Field, 0, len(lg.bound)+len(fields))
+	c.bound = append(c.bound, lg.bound...)
+	c.bound = append(c.bound, fields...)
+	// 预先编码
+	c.boundEncoded = nil
+	c.boundEncoder = nil
+	if enc, ok := c.encoder().(FieldsEncoder); ok && reflect.ValueOf(enc).Comparable() {
+		l := new(Log)
+		enc.EncodeFields(l, c.bound)
+		c.boundEncoded = l.b
+		c.boundEncoder = enc
+	}
+	return c
+}
+
+// clone 返回共享级别的副本，LevelVar 不能复制，所以逐个字段复制
+func (lg *Logger) clone() *Logger {
+	c := new(Logger)
+	c.Writer = lg.Writer
+	c.Header = lg.Header
+	c.Encoder = lg.Encoder
+	c.Extractor = lg.Extractor
+	c.Name = lg.Name
+	c.DisableDebug = lg.DisableDebug
+	c.DisableInfo = lg.DisableInfo
+	c.DisableWarn = lg.DisableWarn
+	c.DisableError = lg.DisableError
+	c.sharedLevel = lg.levelVar()
+	c.bound = lg.bound
+	c.boundEncoded = lg.boundEncoded
+	c.boundEncoder = lg.boundEncoder
+	return c
+}
+
+// levelVar 返回使用的级别
+func (lg *Logger) levelVar() *LevelVar {
+	if lg.sharedLevel != nil {
+		return lg.sharedLevel
+	}
+	return &lg.level
+}
+
+// encoder 返回当前的 Encoder
+func (lg *Logger) encoder() Encoder {
+	if lg.Encoder == nil {
+		return defaultEncoder
+	}
+	return lg.Encoder
+}
+
+// SetLevel 设置最小级别，小于它的日志不输出，可以并发调用
+func (lg *Logger) SetLevel(level Level) {
+	lg.levelVar().Set(level)
+}
+
+// Level 返回最小级别
+func (lg *Logger) Level() Level {
+	return lg.levelVar().Level()
+}
+
+// Enabled 返回 level 的日志是否输出
+func (lg *Logger) Enabled(level Level) bool {
+	if level < lg.levelVar().Level() {
+		return false
+	}
+	switch level {
+	case DebugLevel:
+		return !lg.DisableDebug
+	case InfoLevel:
+		return !lg.DisableInfo
+	case WarnLevel:
+		return !lg.DisableWarn
+	case ErrorLevel:
+		return !lg.DisableError
+	}
+	return true
+}
+
+// output 编码 msg 和 fields ，然后输出，pc 不为 0 时代替 depth
+func (lg *Logger) output(depth int, pc uintptr, level Level, trace string, msg []byte, fields []Field) {
+	e := entryPool.Get().(*Entry)
+	e.Depth = depth + 1
+	e.PC = pc
+	e.Level = level
+	e.Name = lg.Name
+	e.Trace = trace
+	e.Header = lg.Header
+	e.Message = msg
+	e.Bound = lg.bound
+	e.Fields = fields
+	// 编码
 	l := logPool.Get().(*Log)
 	l.b = l.b[:0]
-	// 名称
-	if lg.Name != "" {
-		l.b = append(l.b, lg.Name...)
-	}
-	// 级别
-	l.b = append(l.b, levels[level]...)
-	// 头
-	lg.Header(l, depth)
-	l.b = append(l.b, ' ')
-	// 日志
-	fmt.Fprint(l, args...)
-	// 换行
-	l.b = append(l.b, '\n')
+	l.pc = pc
+	l.e = e
+	enc := lg.encoder()
+	if lg.boundEncoder == enc {
+		e.BoundEncoded = lg.boundEncoded
+	}
+	enc.Encode(l, e)
+	l.e = nil
 	// 输出
-	lg.Writer.Write(l.b)
+	if w, ok := lg.Writer.(EntryWriter); ok {
+		w.WriteEntry(e, l.b)
+	} else {
+		lg.Writer.Write(l.b)
+	}
 	// 回收
+	e.reset()
+	entryPool.Put(e)
 	logPool.Put(l)
 }
 
-func (lg *Logger) printf(depth, level int, format string, args ...any) {
-	l := logPool.Get().(*Log)
-	l.b = l.b[:0]
-	// 名称
-	if lg.Name != "" {
-		l.b = append(l.b, lg.Name...)
-	}
-	// 级别
-	l.b = append(l.b, levels[level]...)
-	// 头
-	lg.Header(l, depth)
-	l.b = append(l.b, ' ')
-	// 日志
-	fmt.Fprintf(l, format, args...)
-	// 换行
-	l.b = append(l.b, '\n')
-	// 输出
-	lg.Writer.Write(l.b)
-	// 回收
-	logPool.Put(l)
+func (lg *Logger) print(depth int, level Level, args ...any) {
+	m := logPool.Get().(*Log)
+	m.b = m.b[:0]
+	fmt.Fprint(m, args...)
+	lg.output(depth, 0, level, "", m.b, nil)
+	logPool.Put(m)
 }
 
-func (lg *Logger) printTrace(depth, level int, trace string, args ...any) {
-	l := logPool.Get().(*Log)
-	l.b = l.b[:0]
-	// 名称
-	if lg.Name != "" {
-		l.b = append(l.b, lg.Name...)
+func (lg *Logger) printf(depth int, level Level, format string, args ...any) {
+	m := logPool.Get().(*Log)
+	m.b = m.b[:0]
+	fmt.Fprintf(m, format, args...)
+	lg.output(depth, 0, level, "", m.b, nil)
+	logPool.Put(m)
+}
+
+func (lg *Logger) printTrace(depth int, level Level, trace string, args ...any) {
+	m := logPool.Get().(*Log)
+	m.b = m.b[:0]
+	fmt.Fprint(m, args...)
+	lg.output(depth, 0, level, trace, m.b, nil)
+	logPool.Put(m)
+}
+
+func (lg *Logger) printfTrace(depth int, level Level, trace, format string, args ...any) {
+	m := logPool.Get().(*Log)
+	m.b = m.b[:0]
+	fmt.Fprintf(m, format, args...)
+	lg.output(depth, 0, level, trace, m.b, nil)
+	logPool.Put(m)
+}
+
+func (lg *Logger) printFields(depth int, level Level, trace, msg string, fields []Field) {
+	m := logPool.Get().(*Log)
+	m.b = append(m.b[:0], msg...)
+	lg.output(depth, 0, level, trace, m.b, fields)
+	logPool.Put(m)
+}
+
+// extract 返回 ctx 中的追踪和字段
+func (lg *Logger) extract(ctx context.Context) (string, []Field) {
+	if ctx == nil {
+		return "", nil
 	}
-	// 级别
-	l.b = append(l.b, levels[level]...)
-	// 头
-	lg.Header(l, depth)
-	l.b = append(l.b, ' ')
-	// 追踪
-	l.b = append(l.b, '[')
-	l.b = append(l.b, trace...)
-	l.b = append(l.b, ']')
-	l.b = append(l.b, ' ')
-	// 日志
-	fmt.Fprint(l, args...)
-	// 换行
-	l.b = append(l.b, '\n')
-	// 输出
-	lg.Writer.Write(l.b)
-	// 回收
-	logPool.Put(l)
+	if lg.Extractor != nil {
+		return lg.Extractor(ctx)
+	}
+	return DefaultContextExtractor(ctx)
 }
 
-func (lg *Logger) printfTrace(depth, level int, trace, format string, args ...any) {
-	l := logPool.Get().(*Log)
-	l.b = l.b[:0]
-	// 名称
-	if lg.Name != "" {
-		l.b = append(l.b, lg.Name...)
+func (lg *Logger) printCtx(depth int, level Level, ctx context.Context, args ...any) {
+	trace, fields := lg.extract(ctx)
+	m := logPool.Get().(*Log)
+	m.b = m.b[:0]
+	fmt.Fprint(m, args...)
+	lg.output(depth, 0, level, trace, m.b, fields)
+	logPool.Put(m)
+}
+
+func (lg *Logger) printfCtx(depth int, level Level, ctx context.Context, format string, args ...any) {
+	trace, fields := lg.extract(ctx)
+	m := logPool.Get().(*Log)
+	m.b = m.b[:0]
+	fmt.Fprintf(m, format, args...)
+	lg.output(depth, 0, level, trace, m.b, fields)
+	logPool.Put(m)
+}
+
+func (lg *Logger) printFieldsCtx(depth int, level Level, ctx context.Context, msg string, fields []Field) {
+	trace, ctxFields := lg.extract(ctx)
+	if len(ctxFields) > 0 {
+		fields = append(ctxFields[:len(ctxFields):len(ctxFields)], fields...)
 	}
-	// 级别
-	l.b = append(l.b, levels[level]...)
-	// 头
-	lg.Header(l, depth)
-	l.b = append(l.b, ' ')
-	// 追踪
-	l.b = append(l.b, '[')
-	l.b = append(l.b, trace...)
-	l.b = append(l.b, ']')
-	l.b = append(l.b, ' ')
-	// 日志
-	fmt.Fprintf(l, format, args...)
-	// 换行
-	l.b = append(l.b, '\n')
-	// 输出
-	lg.Writer.Write(l.b)
-	// 回收
-	logPool.Put(l)
+	m := logPool.Get().(*Log)
+	m.b = append(m.b[:0], msg...)
+	lg.output(depth, 0, level, trace, m.b, fields)
+	logPool.Put(m)
 }
 
 // Recover 如果 recover 不为 nil，输出堆栈
@@ -160,10 +289,13 @@ func (lg *Logger) Recover(recover any) {
 	l.b = l.b[:0]
 	// 名称
 	if lg.Name != "" {
+		l.b = append(l.b, '[')
 		l.b = append(l.b, lg.Name...)
+		l.b = append(l.b, ']')
+		l.b = append(l.b, ' ')
 	}
 	// 级别
-	l.b = append(l.b, levels[panicLevel]...)
+	l.b = append(l.b, levels[PanicLevel]...)
 	FormatTime(l)
 	// recover
 	fmt.Fprintf(l, " %v\n", recover)
@@ -235,224 +367,432 @@ func hasPanicGO(line []byte) bool {
 
 // Debug 输出日志
 func (lg *Logger) Debug(args ...any) {
-	if !lg.DisableDebug {
-		lg.print(loggerDepth, debugLevel, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.print(loggerDepth, DebugLevel, args...)
 	}
 }
 
 // Debugf 输出日志
 func (lg *Logger) Debugf(format string, args ...any) {
-	if !lg.DisableDebug {
-		lg.printf(loggerDepth, debugLevel, format, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.printf(loggerDepth, DebugLevel, format, args...)
 	}
 }
 
 // DebugDepth 输出日志
 func (lg *Logger) DebugDepth(depth int, args ...any) {
-	if !lg.DisableDebug {
-		lg.print(loggerDepth+depth, debugLevel, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.print(loggerDepth+depth, DebugLevel, args...)
 	}
 }
 
 // DebugfDepth 输出日志
 func (lg *Logger) DebugfDepth(depth int, format string, args ...any) {
-	if !lg.DisableDebug {
-		lg.printf(loggerDepth+depth, debugLevel, format, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.printf(loggerDepth+depth, DebugLevel, format, args...)
 	}
 }
 
 // DebugTrace 输出日志
 func (lg *Logger) DebugTrace(traceID string, args ...any) {
-	if !lg.DisableDebug {
-		lg.printTrace(loggerDepth, debugLevel, traceID, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.printTrace(loggerDepth, DebugLevel, traceID, args...)
 	}
 }
 
 // DebugfTrace 输出日志
 func (lg *Logger) DebugfTrace(traceID, format string, args ...any) {
-	if !lg.DisableDebug {
-		lg.printfTrace(loggerDepth, debugLevel, traceID, format, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.printfTrace(loggerDepth, DebugLevel, traceID, format, args...)
 	}
 }
 
 // DebugDepthTrace 输出日志
 func (lg *Logger) DebugDepthTrace(depth int, traceID string, args ...any) {
-	if !lg.DisableDebug {
-		lg.printTrace(loggerDepth+depth, debugLevel, traceID, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.printTrace(loggerDepth+depth, DebugLevel, traceID, args...)
 	}
 }
 
 // DebugfDepthTrace 输出日志
 func (lg *Logger) DebugfDepthTrace(depth int, traceID, format string, args ...any) {
-	if !lg.DisableDebug {
-		lg.printfTrace(loggerDepth+depth, debugLevel, traceID, format, args...)
+	if lg.Enabled(DebugLevel) {
+		lg.printfTrace(loggerDepth+depth, DebugLevel, traceID, format, args...)
 	}
 }
 
 // Info 输出日志
 func (lg *Logger) Info(args ...any) {
-	if !lg.DisableInfo {
-		lg.print(loggerDepth, infoLevel, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.print(loggerDepth, InfoLevel, args...)
 	}
 }
 
 // Infof 输出日志
 func (lg *Logger) Infof(format string, args ...any) {
-	if !lg.DisableInfo {
-		lg.printf(loggerDepth, infoLevel, format, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.printf(loggerDepth, InfoLevel, format, args...)
 	}
 }
 
 // InfoDepth 输出日志
 func (lg *Logger) InfoDepth(depth int, args ...any) {
-	if !lg.DisableInfo {
-		lg.print(loggerDepth+depth, infoLevel, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.print(loggerDepth+depth, InfoLevel, args...)
 	}
 }
 
 // InfofDepth 输出日志
 func (lg *Logger) InfofDepth(depth int, format string, args ...any) {
-	if !lg.DisableInfo {
-		lg.printf(loggerDepth+depth, infoLevel, format, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.printf(loggerDepth+depth, InfoLevel, format, args...)
 	}
 }
 
 // InfoTrace 输出日志
 func (lg *Logger) InfoTrace(traceID string, args ...any) {
-	if !lg.DisableInfo {
-		lg.printTrace(loggerDepth, infoLevel, traceID, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.printTrace(loggerDepth, InfoLevel, traceID, args...)
 	}
 }
 
 // InfofTrace 输出日志
 func (lg *Logger) InfofTrace(traceID, format string, args ...any) {
-	if !lg.DisableInfo {
-		lg.printfTrace(loggerDepth, infoLevel, traceID, format, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.printfTrace(loggerDepth, InfoLevel, traceID, format, args...)
 	}
 }
 
 // InfoDepthTrace 输出日志
 func (lg *Logger) InfoDepthTrace(depth int, traceID string, args ...any) {
-	if !lg.DisableInfo {
-		lg.printTrace(loggerDepth+depth, infoLevel, traceID, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.printTrace(loggerDepth+depth, InfoLevel, traceID, args...)
 	}
 }
 
 // InfofDepthTrace 输出日志
 func (lg *Logger) InfofDepthTrace(depth int, traceID, format string, args ...any) {
-	if !lg.DisableInfo {
-		lg.printfTrace(loggerDepth+depth, infoLevel, traceID, format, args...)
+	if lg.Enabled(InfoLevel) {
+		lg.printfTrace(loggerDepth+depth, InfoLevel, traceID, format, args...)
 	}
 }
 
 // Warn 输出日志
 func (lg *Logger) Warn(args ...any) {
-	if !lg.DisableWarn {
-		lg.print(loggerDepth, warnLevel, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.print(loggerDepth, WarnLevel, args...)
 	}
 }
 
 // Warnf 输出日志
 func (lg *Logger) Warnf(format string, args ...any) {
-	if !lg.DisableWarn {
-		lg.printf(loggerDepth, warnLevel, format, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.printf(loggerDepth, WarnLevel, format, args...)
 	}
 }
 
 // WarnDepth 输出日志
 func (lg *Logger) WarnDepth(depth int, args ...any) {
-	if !lg.DisableWarn {
-		lg.print(loggerDepth+depth, warnLevel, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.print(loggerDepth+depth, WarnLevel, args...)
 	}
 }
 
 // WarnfDepth 输出日志
 func (lg *Logger) WarnfDepth(depth int, format string, args ...any) {
-	if !lg.DisableWarn {
-		lg.printf(loggerDepth+depth, warnLevel, format, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.printf(loggerDepth+depth, WarnLevel, format, args...)
 	}
 }
 
 // WarnTrace 输出日志
 func (lg *Logger) WarnTrace(traceID string, args ...any) {
-	if !lg.DisableWarn {
-		lg.printTrace(loggerDepth, warnLevel, traceID, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.printTrace(loggerDepth, WarnLevel, traceID, args...)
 	}
 }
 
 // WarnfTrace 输出日志
 func (lg *Logger) WarnfTrace(traceID, format string, args ...any) {
-	if !lg.DisableWarn {
-		lg.printfTrace(loggerDepth, warnLevel, traceID, format, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.printfTrace(loggerDepth, WarnLevel, traceID, format, args...)
 	}
 }
 
 // WarnDepthTrace 输出日志
 func (lg *Logger) WarnDepthTrace(depth int, traceID string, args ...any) {
-	if !lg.DisableWarn {
-		lg.printTrace(loggerDepth+depth, warnLevel, traceID, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.printTrace(loggerDepth+depth, WarnLevel, traceID, args...)
 	}
 }
 
 // WarnfDepthTrace 输出日志
 func (lg *Logger) WarnfDepthTrace(depth int, traceID, format string, args ...any) {
-	if !lg.DisableWarn {
-		lg.printfTrace(loggerDepth+depth, warnLevel, traceID, format, args...)
+	if lg.Enabled(WarnLevel) {
+		lg.printfTrace(loggerDepth+depth, WarnLevel, traceID, format, args...)
 	}
 }
 
 // Error 输出日志
 func (lg *Logger) Error(args ...any) {
-	if !lg.DisableError {
-		lg.print(loggerDepth, errorLevel, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.print(loggerDepth, ErrorLevel, args...)
 	}
 }
 
 // Errorf 输出日志
 func (lg *Logger) Errorf(format string, args ...any) {
-	if !lg.DisableError {
-		lg.printf(loggerDepth, errorLevel, format, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.printf(loggerDepth, ErrorLevel, format, args...)
 	}
 }
 
 // ErrorDepth 输出日志
 func (lg *Logger) ErrorDepth(depth int, args ...any) {
-	if !lg.DisableError {
-		lg.print(loggerDepth+depth, errorLevel, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.print(loggerDepth+depth, ErrorLevel, args...)
 	}
 }
 
 // ErrorfDepth 输出日志
 func (lg *Logger) ErrorfDepth(depth int, format string, args ...any) {
-	if !lg.DisableError {
-		lg.printf(loggerDepth+depth, errorLevel, format, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.printf(loggerDepth+depth, ErrorLevel, format, args...)
 	}
 }
 
 // ErrorTrace 输出日志
 func (lg *Logger) ErrorTrace(traceID string, args ...any) {
-	if !lg.DisableError {
-		lg.printTrace(loggerDepth, errorLevel, traceID, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.printTrace(loggerDepth, ErrorLevel, traceID, args...)
 	}
 }
 
 // ErrorfTrace 输出日志
 func (lg *Logger) ErrorfTrace(traceID, format string, args ...any) {
-	if !lg.DisableError {
-		lg.printfTrace(loggerDepth, errorLevel, traceID, format, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.printfTrace(loggerDepth, ErrorLevel, traceID, format, args...)
 	}
 }
 
 // ErrorDepthTrace 输出日志
 func (lg *Logger) ErrorDepthTrace(depth int, traceID string, args ...any) {
-	if !lg.DisableError {
-		lg.printTrace(loggerDepth+depth, errorLevel, traceID, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.printTrace(loggerDepth+depth, ErrorLevel, traceID, args...)
 	}
 }
 
 // ErrorfDepthTrace 输出日志
 func (lg *Logger) ErrorfDepthTrace(depth int, traceID, format string, args ...any) {
-	if !lg.DisableError {
-		lg.printfTrace(loggerDepth+depth, errorLevel, traceID, format, args...)
+	if lg.Enabled(ErrorLevel) {
+		lg.printfTrace(loggerDepth+depth, ErrorLevel, traceID, format, args...)
+	}
+}
+
+// DebugFields 输出带字段的日志
+func (lg *Logger) DebugFields(msg string, fields ...Field) {
+	if lg.Enabled(DebugLevel) {
+		lg.printFields(loggerDepth, DebugLevel, "", msg, fields)
+	}
+}
+
+// DebugFieldsTrace 输出带字段的日志
+func (lg *Logger) DebugFieldsTrace(traceID, msg string, fields ...Field) {
+	if lg.Enabled(DebugLevel) {
+		lg.printFields(loggerDepth, DebugLevel, traceID, msg, fields)
+	}
+}
+
+// InfoFields 输出带字段的日志
+func (lg *Logger) InfoFields(msg string, fields ...Field) {
+	if lg.Enabled(InfoLevel) {
+		lg.printFields(loggerDepth, InfoLevel, "", msg, fields)
+	}
+}
+
+// InfoFieldsTrace 输出带字段的日志
+func (lg *Logger) InfoFieldsTrace(traceID, msg string, fields ...Field) {
+	if lg.Enabled(InfoLevel) {
+		lg.printFields(loggerDepth, InfoLevel, traceID, msg, fields)
+	}
+}
+
+// WarnFields 输出带字段的日志
+func (lg *Logger) WarnFields(msg string, fields ...Field) {
+	if lg.Enabled(WarnLevel) {
+		lg.printFields(loggerDepth, WarnLevel, "", msg, fields)
+	}
+}
+
+// WarnFieldsTrace 输出带字段的日志
+func (lg *Logger) WarnFieldsTrace(traceID, msg string, fields ...Field) {
+	if lg.Enabled(WarnLevel) {
+		lg.printFields(loggerDepth, WarnLevel, traceID, msg, fields)
+	}
+}
+
+// ErrorFields 输出带字段的日志
+func (lg *Logger) ErrorFields(msg string, fields ...Field) {
+	if lg.Enabled(ErrorLevel) {
+		lg.printFields(loggerDepth, ErrorLevel, "", msg, fields)
+	}
+}
+
+// ErrorFieldsTrace 输出带字段的日志
+func (lg *Logger) ErrorFieldsTrace(traceID, msg string, fields ...Field) {
+	if lg.Enabled(ErrorLevel) {
+		lg.printFields(loggerDepth, ErrorLevel, traceID, msg, fields)
+	}
+}
+
+// DebugCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) DebugCtx(ctx context.Context, args ...any) {
+	if lg.Enabled(DebugLevel) {
+		lg.printCtx(loggerDepth, DebugLevel, ctx, args...)
+	}
+}
+
+// DebugfCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) DebugfCtx(ctx context.Context, format string, args ...any) {
+	if lg.Enabled(DebugLevel) {
+		lg.printfCtx(loggerDepth, DebugLevel, ctx, format, args...)
+	}
+}
+
+// DebugFieldsCtx 输出带字段的日志，追踪和字段从 ctx 中提取
+func (lg *Logger) DebugFieldsCtx(ctx context.Context, msg string, fields ...Field) {
+	if lg.Enabled(DebugLevel) {
+		lg.printFieldsCtx(loggerDepth, DebugLevel, ctx, msg, fields)
+	}
+}
+
+// InfoCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) InfoCtx(ctx context.Context, args ...any) {
+	if lg.Enabled(InfoLevel) {
+		lg.printCtx(loggerDepth, InfoLevel, ctx, args...)
+	}
+}
+
+// InfofCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) InfofCtx(ctx context.Context, format string, args ...any) {
+	if lg.Enabled(InfoLevel) {
+		lg.printfCtx(loggerDepth, InfoLevel, ctx, format, args...)
+	}
+}
+
+// InfoFieldsCtx 输出带字段的日志，追踪和字段从 ctx 中提取
+func (lg *Logger) InfoFieldsCtx(ctx context.Context, msg string, fields ...Field) {
+	if lg.Enabled(InfoLevel) {
+		lg.printFieldsCtx(loggerDepth, InfoLevel, ctx, msg, fields)
+	}
+}
+
+// WarnCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) WarnCtx(ctx context.Context, args ...any) {
+	if lg.Enabled(WarnLevel) {
+		lg.printCtx(loggerDepth, WarnLevel, ctx, args...)
+	}
+}
+
+// WarnfCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) WarnfCtx(ctx context.Context, format string, args ...any) {
+	if lg.Enabled(WarnLevel) {
+		lg.printfCtx(loggerDepth, WarnLevel, ctx, format, args...)
+	}
+}
+
+// WarnFieldsCtx 输出带字段的日志，追踪和字段从 ctx 中提取
+func (lg *Logger) WarnFieldsCtx(ctx context.Context, msg string, fields ...Field) {
+	if lg.Enabled(WarnLevel) {
+		lg.printFieldsCtx(loggerDepth, WarnLevel, ctx, msg, fields)
+	}
+}
+
+// ErrorCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) ErrorCtx(ctx context.Context, args ...any) {
+	if lg.Enabled(ErrorLevel) {
+		lg.printCtx(loggerDepth, ErrorLevel, ctx, args...)
+	}
+}
+
+// ErrorfCtx 输出日志，追踪和字段从 ctx 中提取
+func (lg *Logger) ErrorfCtx(ctx context.Context, format string, args ...any) {
+	if lg.Enabled(ErrorLevel) {
+		lg.printfCtx(loggerDepth, ErrorLevel, ctx, format, args...)
+	}
+}
+
+// ErrorFieldsCtx 输出带字段的日志，追踪和字段从 ctx 中提取
+func (lg *Logger) ErrorFieldsCtx(ctx context.Context, msg string, fields ...Field) {
+	if lg.Enabled(ErrorLevel) {
+		lg.printFieldsCtx(loggerDepth, ErrorLevel, ctx, msg, fields)
+	}
+}
+
+// Panic 输出日志，然后使用日志内容 panic
+func (lg *Logger) Panic(args ...any) {
+	msg := fmt.Sprint(args...)
+	if lg.Enabled(PanicLevel) {
+		lg.printFields(loggerDepth, PanicLevel, "", msg, nil)
+	}
+	panic(msg)
+}
+
+// Panicf 输出日志，然后使用日志内容 panic
+func (lg *Logger) Panicf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if lg.Enabled(PanicLevel) {
+		lg.printFields(loggerDepth, PanicLevel, "", msg, nil)
+	}
+	panic(msg)
+}
+
+// PanicTrace 输出日志，然后使用日志内容 panic
+func (lg *Logger) PanicTrace(traceID string, args ...any) {
+	msg := fmt.Sprint(args...)
+	if lg.Enabled(PanicLevel) {
+		lg.printFields(loggerDepth, PanicLevel, traceID, msg, nil)
+	}
+	panic(msg)
+}
+
+// PanicfTrace 输出日志，然后使用日志内容 panic
+func (lg *Logger) PanicfTrace(traceID, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if lg.Enabled(PanicLevel) {
+		lg.printFields(loggerDepth, PanicLevel, traceID, msg, nil)
+	}
+	panic(msg)
+}
+
+// Fatal 输出日志，同步输出，然后调用 Exit(1)
+func (lg *Logger) Fatal(args ...any) {
+	if lg.Enabled(FatalLevel) {
+		lg.print(loggerDepth, FatalLevel, args...)
+	}
+	lg.exit()
+}
+
+// Fatalf 输出日志，同步输出，然后调用 Exit(1)
+func (lg *Logger) Fatalf(format string, args ...any) {
+	if lg.Enabled(FatalLevel) {
+		lg.printf(loggerDepth, FatalLevel, format, args...)
+	}
+	lg.exit()
+}
+
+// FatalTrace 输出日志，同步输出，然后调用 Exit(1)
+func (lg *Logger) FatalTrace(traceID string, args ...any) {
+	if lg.Enabled(FatalLevel) {
+		lg.printTrace(loggerDepth, FatalLevel, traceID, args...)
+	}
+	lg.exit()
+}
+
+// FatalfTrace 输出日志，同步输出，然后调用 Exit(1)
+func (lg *Logger) FatalfTrace(traceID, format string, args ...any) {
+	if lg.Enabled(FatalLevel) {
+		lg.printfTrace(loggerDepth, FatalLevel, traceID, format, args...)
 	}
+	lg.exit()
 }
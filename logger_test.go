@@ -3,6 +3,7 @@ package log
 import (
 	"log"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -105,3 +106,80 @@ func testRecover1() {
 func testRecover2() {
 	panic("test recover")
 }
+
+func Test_LoggerCaller(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, FileNameHeader, "")
+	lg.Info(1)
+	lg.Infof("%d", 1)
+	lg.InfoTrace("t", 1)
+	lg.InfofTrace("t", "%d", 1)
+	lg.InfoFields("1")
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, " logger_test.go:") {
+			t.Fatal(line)
+		}
+	}
+}
+
+func Test_LoggerChild(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "api")
+	db := lg.Named("db").With(String("table", "user"))
+	db.With(Int("id", 1)).Info("select")
+	if !strings.HasPrefix(buf.String(), "[api.db] [I] ") ||
+		!strings.HasSuffix(buf.String(), " select table=user id=1\n") {
+		t.Fatal(buf.String())
+	}
+	// 共享级别
+	buf.Reset()
+	lg.SetLevel(ErrorLevel)
+	db.Warn("warn")
+	if buf.Len() != 0 || db.Level() != ErrorLevel {
+		t.FailNow()
+	}
+	// 父 Logger 没有字段
+	lg.Error("parent")
+	if !strings.HasPrefix(buf.String(), "[api] [E] ") ||
+		!strings.HasSuffix(buf.String(), " parent\n") {
+		t.Fatal(buf.String())
+	}
+	// 修改 Encoder 之后不使用预先编码的字段
+	buf.Reset()
+	db.Encoder = &JSONEncoder{}
+	db.ErrorFields("json", Int("n", 2))
+	if !strings.HasSuffix(buf.String(), `"msg":"json","table":"user","n":2}`+"\n") ||
+		!strings.Contains(buf.String(), `"logger":"api.db"`) {
+		t.Fatal(buf.String())
+	}
+}
+
+// mapEncoder 是不能比较的 Encoder
+type mapEncoder struct {
+	m map[string]string
+}
+
+func (e mapEncoder) Encode(l *Log, en *Entry) {
+	TextEncoder{}.Encode(l, en)
+}
+
+func (e mapEncoder) EncodeFields(l *Log, fields []Field) {
+	l.Fields(fields)
+}
+
+func Test_LoggerWithUncomparableEncoder(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, DefaultHeader, "")
+	lg.Encoder = mapEncoder{}
+	lg.With(String("k", "v")).Info("a")
+	if !strings.HasSuffix(buf.String(), " a k=v\n") {
+		t.Fatal(buf.String())
+	}
+	// 接口中保存的值不能比较
+	buf.Reset()
+	lg.Encoder = struct{ FieldsEncoder }{mapEncoder{}}
+	lg.With(String("k", "v")).Info("b")
+	if !strings.HasSuffix(buf.String(), " b k=v\n") {
+		t.Fatal(buf.String())
+	}
+}
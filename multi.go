@@ -0,0 +1,65 @@
+package log
+
+import (
+	"errors"
+	"io"
+)
+
+// MultiWriter 将日志写入多个输出，和 io.MultiWriter 不同的是，
+// 它实现了 EntryWriter ，Flusher 和 io.Closer ，会传递给每一个输出。
+// 一个输出出错不影响其他的输出。
+type MultiWriter []io.Writer
+
+// Write 实现 io.Writer
+func (m MultiWriter) Write(b []byte) (int, error) {
+	var errs []error
+	for _, w := range m {
+		if _, err := w.Write(b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(b), errors.Join(errs...)
+}
+
+// WriteEntry 实现 EntryWriter
+func (m MultiWriter) WriteEntry(e *Entry, b []byte) (int, error) {
+	// 下一层的 WriteEntry 不能再使用 depth
+	if e.PC == 0 {
+		e.PC = e.CallerPC()
+	}
+	var errs []error
+	for _, w := range m {
+		var err error
+		if ew, ok := w.(EntryWriter); ok {
+			_, err = ew.WriteEntry(e, b)
+		} else {
+			_, err = w.Write(b)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(b), errors.Join(errs...)
+}
+
+// Flush 实现 Flusher
+func (m MultiWriter) Flush() error {
+	var errs []error
+	for _, w := range m {
+		if f, ok := w.(Flusher); ok {
+			errs = append(errs, f.Flush())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 实现 io.Closer
+func (m MultiWriter) Close() error {
+	var errs []error
+	for _, w := range m {
+		if c, ok := w.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,577 @@
+package log
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 默认的重连最小间隔
+	defaultNetMinBackoff = 100 * time.Millisecond
+	// 默认的重连最大间隔
+	defaultNetMaxBackoff = 30 * time.Second
+	// 默认的超时
+	defaultNetTimeout = 10 * time.Second
+	// 重放的时候，流式连接每次写入的最大字节
+	netReplayBatch = 32 * 1024
+	// 缓存目录中保存重放中断的文件和位置的文件
+	netReplayFile = ".replay"
+)
+
+var (
+	errNetClosed      = errors.New("net has been closed")
+	errNetUnavailable = errors.New("net is unavailable")
+)
+
+// NetConfig 是 NewNet 的参数。
+type NetConfig struct {
+	// 网络，tcp/udp/unix/unixgram
+	Network string `json:"network" yaml:"network" validate:"required,oneof=tcp udp unix unixgram"`
+	// 地址
+	Addr string `json:"addr" yaml:"addr" validate:"required"`
+	// 不为 nil 的时候 tcp 使用 TLS
+	TLS *NetTLSConfig `json:"tls" yaml:"tls"`
+	// 连接和写入的超时，单位毫秒，默认是 10000
+	Timeout int `json:"timeout" yaml:"timeout" validate:"omitempty,min=1"`
+	// 重连的最小间隔，单位毫秒，每次失败翻倍，默认是 100
+	MinBackoff int `json:"minBackoff" yaml:"minBackoff" validate:"omitempty,min=1"`
+	// 重连的最大间隔，单位毫秒，默认是 30000
+	MaxBackoff int `json:"maxBackoff" yaml:"maxBackoff" validate:"omitempty,min=1"`
+	// 不为 nil 的时候，连接不可用期间的日志使用 File 保存到这个目录，重连之后按顺序重放。
+	// FileName ，Compress ，Path 和 Std 会被忽略，文件名总是 {time} ，
+	// 可以使用 MaxTotalSize 和 MaxFiles 限制磁盘的使用，
+	// MaxKeepDay ，MaxTotalSize 和 MaxFiles 删除的文件，包括还没有重放的，不会再发送
+	Spool *FileConfig `json:"spool" yaml:"spool"`
+}
+
+// NetTLSConfig 是 NetConfig 的 TLS 配置
+type NetTLSConfig struct {
+	// 验证服务端证书的 CA 文件，空使用系统的
+	CAFile string `json:"caFile" yaml:"caFile"`
+	// 客户端证书和私钥文件，双向认证的时候使用
+	CertFile string `json:"certFile" yaml:"certFile"`
+	KeyFile  string `json:"keyFile" yaml:"keyFile"`
+	// 服务端的名称，空使用地址的主机名
+	ServerName string `json:"serverName" yaml:"serverName"`
+	// 是否不验证服务端证书
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+}
+
+// tlsConfig 返回 tls.Config
+func (c *NetTLSConfig) tlsConfig() (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %s", c.CAFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// NewNet 返回一个 Net 实例，第一次连接失败不返回错误，在后台重连。
+// 如果 Spool 目录中有上一次没有发送的日志，连接之后先重放。
+func NewNet(conf *NetConfig) (*Net, error) {
+	n := new(Net)
+	n.network = conf.Network
+	n.addr = conf.Addr
+	switch n.network {
+	case "tcp", "unix":
+		n.stream = true
+	case "udp", "unixgram":
+	default:
+		return nil, fmt.Errorf("unknown network %q", n.network)
+	}
+	if n.addr == "" {
+		return nil, errors.New("net addr is empty")
+	}
+	if conf.TLS != nil {
+		if n.network != "tcp" {
+			return nil, errors.New("net tls needs tcp")
+		}
+		var err error
+		n.tls, err = conf.TLS.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		if n.tls.ServerName == "" {
+			n.tls.ServerName, _, _ = net.SplitHostPort(n.addr)
+		}
+	}
+	n.timeout = time.Duration(conf.Timeout) * time.Millisecond
+	if n.timeout <= 0 {
+		n.timeout = defaultNetTimeout
+	}
+	n.minBackoff = time.Duration(conf.MinBackoff) * time.Millisecond
+	if n.minBackoff <= 0 {
+		n.minBackoff = defaultNetMinBackoff
+	}
+	n.maxBackoff = time.Duration(conf.MaxBackoff) * time.Millisecond
+	if n.maxBackoff <= 0 {
+		n.maxBackoff = defaultNetMaxBackoff
+	}
+	if n.maxBackoff < n.minBackoff {
+		n.maxBackoff = n.minBackoff
+	}
+	// 缓存
+	if conf.Spool != nil {
+		sc := *conf.Spool
+		sc.FileName = ""
+		sc.Compress = ""
+		sc.Path = ""
+		sc.Std = ""
+		n.spoolConf = &sc
+		// 先检查配置
+		if _, err := ParseSize(sc.MaxFileSize); err != nil {
+			return nil, err
+		}
+		// 上一次没有发送的
+		n.spoolRoot = sc.RootDir
+		n.loadReplay()
+		files, err := n.spoolFiles()
+		if err == nil && len(files) > 0 {
+			n.spool, err = NewFile(n.spoolConf)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	n.exit = make(chan struct{})
+	n.reconnect = make(chan struct{}, 1)
+	// 没有缓存的时候，先连接一次
+	if n.spool == nil {
+		conn, err := n.dial()
+		if err == nil {
+			n.conn = conn
+		}
+	}
+	n.wait.Add(1)
+	go n.loop()
+	if n.conn == nil {
+		n.broken()
+	}
+	return n, nil
+}
+
+// Net 实现了 io.Writer 接口，可以作为 Logger 的输出，
+// 每一次 Write 是一行，流式连接直接写入，数据报连接一行是一个数据报。
+// 写入成功只表示数据进入了系统的发送缓存，连接断开的时候，最后写入的几行可能会丢失。
+// 连接断开之后，后台协程按照指数退避重连，期间的日志保存到 Spool 目录，
+// 没有设置 Spool 的时候丢弃。重连之后，先按顺序重放保存的日志，然后再直接写入。
+// 重放中断的时候，中断的文件和位置保存在 Spool 目录中，下一次，包括重启之后，从这个位置继续，
+// 中断之前已经写入连接的，可能没有到达服务端，所以是至少一次，不是恰好一次。
+// 进程崩溃的时候来不及保存位置，正在重放的文件会从头开始重复发送。
+type Net struct {
+	lock sync.Mutex
+	wait sync.WaitGroup
+	// 退出协程通知
+	exit chan struct{}
+	// 是否已关闭标志
+	closed bool
+	// 网络和地址
+	network string
+	addr    string
+	stream  bool
+	tls     *tls.Config
+	// 超时
+	timeout time.Duration
+	// 重连的间隔
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	// 当前的连接，nil 表示不可用
+	conn net.Conn
+	// 通知重连
+	reconnect chan struct{}
+	// 缓存的配置和目录
+	spoolConf *FileConfig
+	spoolRoot string
+	// 不为 nil 表示正在缓存
+	spool *File
+	// 重放中断的文件和位置，相对于 spoolRoot ，只在协程中使用
+	replayPath   string
+	replayOffset int64
+	// 重放的位置是否保存在了文件中
+	replaySaved bool
+	// 是否已经输出了写入的错误，写入成功之后重置
+	errReported bool
+	// 丢弃的行数
+	dropped atomic.Uint64
+}
+
+// Write 实现 io.Writer 。
+func (n *Net) Write(b []byte) (int, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.closed {
+		return 0, errNetClosed
+	}
+	if n.conn != nil {
+		n.conn.SetWriteDeadline(time.Now().Add(n.timeout))
+		_, err := n.conn.Write(b)
+		if err == nil {
+			n.errReported = false
+			return len(b), nil
+		}
+		// 只输出断开之后的第一个错误
+		if !n.errReported {
+			n.errReported = true
+			fmt.Fprintln(os.Stderr, err)
+		}
+		n.conn.Close()
+		n.conn = nil
+		n.broken()
+	}
+	// 不可用
+	if n.spoolConf == nil {
+		n.dropped.Add(1)
+		return 0, errNetUnavailable
+	}
+	if n.spool == nil {
+		var err error
+		n.spool, err = NewFile(n.spoolConf)
+		if err != nil {
+			n.dropped.Add(1)
+			return 0, err
+		}
+	}
+	return n.spool.Write(b)
+}
+
+// Dropped 返回丢弃的行数
+func (n *Net) Dropped() uint64 {
+	return n.dropped.Load()
+}
+
+// Flush 实现 Flusher 接口，同步缓存的数据到磁盘。
+func (n *Net) Flush() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.closed {
+		return errNetClosed
+	}
+	if n.spool != nil {
+		return n.spool.Flush()
+	}
+	return nil
+}
+
+// Close 实现 io.Closer 接口，关闭连接，等待协程退出，没有发送的保存在 Spool 目录中。
+func (n *Net) Close() error {
+	n.lock.Lock()
+	if n.closed {
+		n.lock.Unlock()
+		return errNetClosed
+	}
+	n.closed = true
+	n.lock.Unlock()
+	close(n.exit)
+	n.wait.Wait()
+	// 协程已经退出
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	var errs []error
+	if n.conn != nil {
+		errs = append(errs, n.conn.Close())
+		n.conn = nil
+	}
+	if n.spool != nil {
+		errs = append(errs, n.spool.Close())
+		n.spool = nil
+	}
+	return errors.Join(errs...)
+}
+
+// broken 通知协程重连
+func (n *Net) broken() {
+	select {
+	case n.reconnect <- struct{}{}:
+	default:
+	}
+}
+
+// loop 运行在一个协程中，连接断开之后重连和重放
+func (n *Net) loop() {
+	defer n.wait.Done()
+	for {
+		select {
+		case <-n.exit:
+			return
+		case <-n.reconnect:
+		}
+		backoff := n.minBackoff
+		for {
+			conn, err := n.dial()
+			if err == nil {
+				err = n.replay(conn)
+				if err == nil {
+					break
+				}
+				conn.Close()
+			}
+			select {
+			case <-n.exit:
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > n.maxBackoff {
+				backoff = n.maxBackoff
+			}
+		}
+	}
+}
+
+// dial 创建连接
+func (n *Net) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: n.timeout}
+	if n.tls != nil {
+		return tls.DialWithDialer(dialer, n.network, n.addr, n.tls)
+	}
+	return dialer.Dial(n.network, n.addr)
+}
+
+// replay 按顺序发送缓存的日志，完成之后使用 conn 直接写入。
+// 每一次换新的缓存文件，发送旧的，直到当前的文件是空的，
+// 最后一次在锁中发送，保证顺序。
+func (n *Net) replay(conn net.Conn) error {
+	for {
+		n.lock.Lock()
+		if n.closed {
+			n.lock.Unlock()
+			return errNetClosed
+		}
+		sp := n.spool
+		if sp == nil {
+			n.conn = conn
+			n.lock.Unlock()
+			return nil
+		}
+		// 当前的文件不是空的就换新文件
+		cur, rotated := sp.rotateNonEmpty()
+		if rotated {
+			n.lock.Unlock()
+			err := n.sendSpool(conn, cur)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		// 新的日志在等待，发送剩下的
+		err := n.sendSpool(conn, cur)
+		if err == nil {
+			sp.Close()
+			n.spool = nil
+			n.removeSpool()
+			n.conn = conn
+		}
+		n.lock.Unlock()
+		return err
+	}
+}
+
+// spoolFiles 返回缓存目录中所有的文件，按时间排序
+func (n *Net) spoolFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(n.spoolRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() && !isCompressed(d.Name()) && d.Name() != netReplayFile {
+			files = append(files, path)
+		}
+		return nil
+	})
+	// 日期目录和 {time} 的文件名，字符顺序就是时间顺序
+	sort.Strings(files)
+	return files, err
+}
+
+// sendSpool 发送缓存目录中除了 cur 之外的文件，发送完的删除
+func (n *Net) sendSpool(conn net.Conn, cur string) error {
+	files, err := n.spoolFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		if path == cur {
+			continue
+		}
+		select {
+		case <-n.exit:
+			return errNetClosed
+		default:
+		}
+		err = n.sendFile(conn, path)
+		if err != nil {
+			return err
+		}
+		// 可能已经被 File 的清理删除了
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// 删除空的日期目录
+		if dir := filepath.Dir(path); dir != filepath.Clean(n.spoolRoot) {
+			os.Remove(dir)
+		}
+	}
+	return nil
+}
+
+// sendFile 发送一个缓存文件，从上一次中断的位置开始，中断的时候保存位置
+func (n *Net) sendFile(conn net.Conn, path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		// 已经被 File 的清理删除了
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	rel, err := filepath.Rel(n.spoolRoot, path)
+	if err != nil {
+		return err
+	}
+	if n.replayPath != rel {
+		n.replayPath = rel
+		n.replayOffset = 0
+	}
+	defer func() {
+		if err != nil {
+			n.saveReplay()
+		}
+	}()
+	_, err = f.Seek(n.replayOffset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	var batch []byte
+	write := func() error {
+		if len(batch) < 1 {
+			return nil
+		}
+		conn.SetWriteDeadline(time.Now().Add(n.timeout))
+		_, err := conn.Write(batch)
+		if err != nil {
+			return err
+		}
+		n.replayOffset += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		line, err := r.ReadSlice('\n')
+		batch = append(batch, line...)
+		if err == bufio.ErrBufferFull {
+			// 很长的一行
+			continue
+		}
+		if err == io.EOF {
+			err = write()
+			if err != nil {
+				return err
+			}
+			n.replayPath = ""
+			n.replayOffset = 0
+			if n.replaySaved {
+				n.replaySaved = false
+				os.Remove(filepath.Join(n.spoolRoot, netReplayFile))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// 数据报一行一个
+		if !n.stream || len(batch) >= netReplayBatch {
+			err = write()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// loadReplay 读取上一次重放中断的文件和位置，格式是 "offset path"
+func (n *Net) loadReplay() {
+	d, err := os.ReadFile(filepath.Join(n.spoolRoot, netReplayFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return
+	}
+	offset, path, ok := strings.Cut(strings.TrimSpace(string(d)), " ")
+	if !ok {
+		return
+	}
+	n.replayOffset, err = strconv.ParseInt(offset, 10, 64)
+	if err != nil || n.replayOffset < 0 {
+		n.replayOffset = 0
+		return
+	}
+	n.replayPath = path
+	n.replaySaved = true
+}
+
+// saveReplay 保存重放中断的文件和位置
+func (n *Net) saveReplay() {
+	if n.replayPath == "" || n.replayOffset < 1 {
+		return
+	}
+	err := os.WriteFile(filepath.Join(n.spoolRoot, netReplayFile),
+		[]byte(strconv.FormatInt(n.replayOffset, 10)+" "+n.replayPath+"\n"), 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	n.replaySaved = true
+}
+
+// removeSpool 删除缓存目录中剩下的空文件，空目录和重放的位置
+func (n *Net) removeSpool() {
+	os.Remove(filepath.Join(n.spoolRoot, netReplayFile))
+	files, err := n.spoolFiles()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	for _, path := range files {
+		os.Remove(path)
+		if dir := filepath.Dir(path); dir != filepath.Clean(n.spoolRoot) {
+			os.Remove(dir)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_NetSpool(t *testing.T) {
+	// unix socket 的路径不能太长
+	dir, err := os.MkdirTemp("", "net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := &NetConfig{
+		Network:    "unix",
+		Addr:       filepath.Join(dir, "s.sock"),
+		MinBackoff: 10,
+		MaxBackoff: 20,
+		Spool: &FileConfig{
+			RootDir:      filepath.Join(dir, "spool"),
+			MaxKeepDay:   1,
+			SyncInterval: 10,
+			MaxFileSize:  "100",
+		},
+	}
+	// 不可用，保存到磁盘
+	n, err := NewNet(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(n, "%d\n", i)
+	}
+	n.Close()
+	if len(readDir(t, conf.Spool.RootDir)) < 1 {
+		t.FailNow()
+	}
+	// 重新创建，继续保存
+	n, err = NewNet(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	for i := 50; i < 100; i++ {
+		fmt.Fprintf(n, "%d\n", i)
+	}
+	// 服务端启动之后重放
+	lis, err := net.Listen("unix", conf.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	lines := make(chan string, 200)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewScanner(conn)
+		for r.Scan() {
+			lines <- r.Text()
+		}
+	}()
+	for i := 100; i < 150; i++ {
+		fmt.Fprintf(n, "%d\n", i)
+		time.Sleep(time.Millisecond)
+	}
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 150; i++ {
+		select {
+		case line := <-lines:
+			if line != strconv.Itoa(i) {
+				t.Fatal(i, line)
+			}
+		case <-timeout:
+			t.Fatal("timeout", i)
+		}
+	}
+	// 重放完删除
+	n.lock.Lock()
+	spooling := n.spool != nil
+	n.lock.Unlock()
+	if spooling {
+		t.FailNow()
+	}
+	if files, _ := n.spoolFiles(); len(files) != 0 {
+		t.Fatal(files)
+	}
+	if n.Dropped() != 0 {
+		t.FailNow()
+	}
+}
+
+func Test_NetDrop(t *testing.T) {
+	dir := t.TempDir()
+	n, err := NewNet(&NetConfig{
+		Network: "unix",
+		Addr:    filepath.Join(dir, "s.sock"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = n.Write([]byte("a\n")); err != errNetUnavailable {
+		t.Fatal(err)
+	}
+	if n.Dropped() != 1 {
+		t.FailNow()
+	}
+	n.Close()
+	if _, err = n.Write([]byte("a\n")); err != errNetClosed {
+		t.Fatal(err)
+	}
+}
+
+func Test_NetWriteError(t *testing.T) {
+	// 替换 os.Stderr 得到输出的错误
+	stderr := os.Stderr
+	defer func() { os.Stderr = stderr }()
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	os.Stderr = f
+	// 没有协程
+	n := &Net{reconnect: make(chan struct{}, 1), timeout: time.Second}
+	closedConn := func() net.Conn {
+		c1, c2 := net.Pipe()
+		c1.Close()
+		c2.Close()
+		return c1
+	}
+	// 断开之后只输出一次
+	for i := 0; i < 3; i++ {
+		n.conn = closedConn()
+		n.Write([]byte("a\n"))
+	}
+	// 写入成功之后，再次断开的时候输出
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	go bufio.NewReader(c2).ReadString('\n')
+	n.conn = c1
+	if _, err := n.Write([]byte("b\n")); err != nil {
+		t.Fatal(err)
+	}
+	n.conn = closedConn()
+	n.Write([]byte("c\n"))
+	d, _ := os.ReadFile(f.Name())
+	if lines := strings.Count(string(d), "\n"); lines != 2 || n.Dropped() != 4 {
+		t.Fatalf("%q %d", d, n.Dropped())
+	}
+}
+
+func Test_NetReplayOffset(t *testing.T) {
+	dir, err := os.MkdirTemp("", "net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "spool")
+	// 上一次重放到了第 3 行
+	name := filepath.Join("20000101", "20000101000000.000000")
+	err = os.MkdirAll(filepath.Join(root, "20000101"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, name), []byte("0\n1\n2\n3\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, netReplayFile), []byte("4 "+name+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := filepath.Join(dir, "s.sock")
+	lis, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewScanner(conn)
+		for r.Scan() {
+			lines <- r.Text()
+		}
+	}()
+	n, err := NewNet(&NetConfig{
+		Network:    "unix",
+		Addr:       addr,
+		MinBackoff: 10,
+		Spool: &FileConfig{
+			RootDir:      root,
+			MaxKeepDay:   1,
+			SyncInterval: 10,
+			MaxFileSize:  "1M",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	fmt.Fprintf(n, "4\n")
+	timeout := time.After(5 * time.Second)
+	for i := 2; i < 5; i++ {
+		select {
+		case line := <-lines:
+			if line != strconv.Itoa(i) {
+				t.Fatal(i, line)
+			}
+		case <-timeout:
+			t.Fatal("timeout", i)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, netReplayFile)); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
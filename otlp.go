@@ -0,0 +1,472 @@
+package log
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 默认的地址
+	defaultOTLPURL = "http://localhost:4318/v1/logs"
+)
+
+var (
+	// 级别对应的 SeverityNumber ，debug/info/warn/error/panic/fatal
+	otlpSeverities = []int{5, 9, 13, 17, 21, 24}
+)
+
+// OTLPConfig 是 NewOTLP 的参数。
+type OTLPConfig struct {
+	// 地址，默认是 http://localhost:4318/v1/logs
+	URL string `json:"url" yaml:"url"`
+	// 编码，protobuf/json ，默认是 protobuf
+	Encoding string `json:"encoding" yaml:"encoding" validate:"omitempty,oneof=protobuf json"`
+	// 请求头，比如 Authorization
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	// resource 的 service.name ，默认是 AppName
+	ServiceName string `json:"serviceName" yaml:"serviceName"`
+	// resource 的其他属性
+	ResourceAttributes map[string]string `json:"resourceAttributes" yaml:"resourceAttributes"`
+	// 是否写入 code.filepath ，code.lineno 和 code.function 属性
+	Caller bool `json:"caller" yaml:"caller"`
+	// 是否使用 gzip 压缩请求
+	Gzip bool `json:"gzip" yaml:"gzip"`
+	// 以下和 HTTPConfig 相同
+	BatchSize  string `json:"batchSize" yaml:"batchSize"`
+	BatchCount int    `json:"batchCount" yaml:"batchCount" validate:"omitempty,min=1"`
+	Linger     int    `json:"linger" yaml:"linger" validate:"omitempty,min=1"`
+	QueueSize  int    `json:"queueSize" yaml:"queueSize" validate:"omitempty,min=1"`
+	Drop       string `json:"drop" yaml:"drop" validate:"omitempty,oneof=newest oldest"`
+	Retry      int    `json:"retry" yaml:"retry"`
+	Backoff    int    `json:"backoff" yaml:"backoff" validate:"omitempty,min=1"`
+	MaxBackoff int    `json:"maxBackoff" yaml:"maxBackoff" validate:"omitempty,min=1"`
+	Timeout    int    `json:"timeout" yaml:"timeout" validate:"omitempty,min=1"`
+}
+
+// OTLP 使用 OTLP/HTTP 发送日志，批次，重试和丢弃和 HTTP 相同。
+// WriteEntry 的 Entry.Message 是 body ，Logger.With 绑定的字段和 Fields 是 attributes ，
+// Logger 的名称是 instrumentation scope 。
+// 追踪是 W3C traceparent ，32 位十六进制的 trace id ，或者 trace id 加上 '-' ，':' 和 16 位十六进制的 span id 的时候，
+// 写入 trace_id 和 span_id ，其他的写入 trace 属性。
+// Write 的一行日志是 body ，从 "[name] [level] " 前缀中解析级别。
+type OTLP struct {
+	*HTTP
+}
+
+// NewOTLP 返回一个 OTLP 实例。
+func NewOTLP(conf *OTLPConfig) (*OTLP, error) {
+	format := "otlp"
+	switch conf.Encoding {
+	case "", "protobuf":
+	case "json":
+		format = "otlp-json"
+	default:
+		return nil, fmt.Errorf("unknown otlp encoding %q", conf.Encoding)
+	}
+	hc := &HTTPConfig{
+		URL:        conf.URL,
+		Headers:    conf.Headers,
+		Gzip:       conf.Gzip,
+		BatchSize:  conf.BatchSize,
+		BatchCount: conf.BatchCount,
+		Linger:     conf.Linger,
+		QueueSize:  conf.QueueSize,
+		Drop:       conf.Drop,
+		Retry:      conf.Retry,
+		Backoff:    conf.Backoff,
+		MaxBackoff: conf.MaxBackoff,
+		Timeout:    conf.Timeout,
+	}
+	if hc.URL == "" {
+		hc.URL = defaultOTLPURL
+	}
+	// resource
+	enc := &otlpEncoder{caller: conf.Caller}
+	name := conf.ServiceName
+	if name == "" {
+		name = conf.ResourceAttributes["service.name"]
+	}
+	if name == "" {
+		name = AppName
+	}
+	enc.resource = append(enc.resource, String("service.name", name))
+	for k, v := range conf.ResourceAttributes {
+		if k != "service.name" {
+			enc.resource = append(enc.resource, String(k, v))
+		}
+	}
+	sort.Slice(enc.resource[1:], func(i, j int) bool {
+		return enc.resource[i+1].Key < enc.resource[j+1].Key
+	})
+	h, err := newHTTP(hc, format, enc)
+	if err != nil {
+		return nil, err
+	}
+	return &OTLP{HTTP: h}, nil
+}
+
+// WriteEntry 实现 EntryWriter ，使用 Entry 的级别，名称，追踪，消息和字段。
+func (o *OTLP) WriteEntry(e *Entry, b []byte) (int, error) {
+	var pc uintptr
+	if o.otlp.caller {
+		pc = e.CallerPC()
+	}
+	return len(b), o.push(o.otlp.message(e, pc))
+}
+
+// otlpEncoder 编码 ExportLogsServiceRequest
+type otlpEncoder struct {
+	// resource 的属性
+	resource []Field
+	// 是否写入调用者
+	caller bool
+}
+
+// message 复制 e 的数据，发送协程中编码，
+// 所以 Any 和 Err 这些调用者之后可能修改的值，在这里转换成字符串
+func (o *otlpEncoder) message(e *Entry, pc uintptr) *httpMessage {
+	m := &httpMessage{
+		line:  append([]byte(nil), e.Message...),
+		time:  Now(),
+		level: e.Level,
+		name:  e.Name,
+		trace: e.Trace,
+	}
+	if len(e.Bound)+len(e.Fields) > 0 {
+		m.fields = make([]Field, 0, len(e.Bound)+len(e.Fields))
+		m.fields = otlpAppendFields(m.fields, e.Bound)
+		m.fields = otlpAppendFields(m.fields, e.Fields)
+	}
+	m.pc = pc
+	return m
+}
+
+// otlpAppendFields 添加 fields 的副本，不是标量的值转换成字符串
+func otlpAppendFields(dst, fields []Field) []Field {
+	for i := range fields {
+		f := &fields[i]
+		switch f.Type {
+		case StringType, BoolType, IntType, UintType, FloatType:
+			dst = append(dst, *f)
+		default:
+			dst = append(dst, String(f.Key, otlpString(f)))
+		}
+	}
+	return dst
+}
+
+// otlpScope 是一个 instrumentation scope 的日志
+type otlpScope struct {
+	name    string
+	records []*httpMessage
+}
+
+// scopes 按照 Logger 的名称分组
+func otlpScopes(batch []*httpMessage) []*otlpScope {
+	var scopes []*otlpScope
+	names := make(map[string]*otlpScope)
+	for _, m := range batch {
+		s := names[m.name]
+		if s == nil {
+			s = &otlpScope{name: m.name}
+			names[m.name] = s
+			scopes = append(scopes, s)
+		}
+		s.records = append(s.records, m)
+	}
+	return scopes
+}
+
+// attributes 返回 m 的属性，追踪不能解析的时候是 trace 属性
+func (o *otlpEncoder) attributes(m *httpMessage, traceOK bool) []Field {
+	attrs := m.fields
+	if m.pc != 0 {
+		f := pcFrame(m.pc)
+		attrs = append(attrs[:len(attrs):len(attrs)],
+			String("code.filepath", f.File),
+			Int("code.lineno", f.Line),
+			String("code.function", f.Function))
+	}
+	if m.trace != "" && !traceOK {
+		attrs = append(attrs[:len(attrs):len(attrs)], String("trace", m.trace))
+	}
+	return attrs
+}
+
+// parseTraceContext 解析追踪中的 trace id 和 span id ，
+// 支持 W3C traceparent ，trace id ，trace id 加上 '-' ，':' 和 span id
+func parseTraceContext(s string) (traceID []byte, spanID []byte, ok bool) {
+	// traceparent ，00-trace-span-flags
+	if len(s) == 55 && s[2] == '-' && s[35] == '-' && s[52] == '-' {
+		s = s[3:52]
+	}
+	if len(s) != 32 && !(len(s) == 49 && (s[32] == '-' || s[32] == ':')) {
+		return nil, nil, false
+	}
+	traceID, err := hex.DecodeString(s[:32])
+	if err != nil || isZeroBytes(traceID) {
+		return nil, nil, false
+	}
+	if len(s) == 49 {
+		spanID, err = hex.DecodeString(s[33:])
+		if err != nil || isZeroBytes(spanID) {
+			return nil, nil, false
+		}
+	}
+	return traceID, spanID, true
+}
+
+// isZeroBytes 返回 b 是否都是 0
+func isZeroBytes(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// otlpSeverity 返回级别的 SeverityNumber 和 SeverityText
+func otlpSeverity(level Level) (int, string) {
+	if level >= 0 && int(level) < len(otlpSeverities) {
+		return otlpSeverities[level], strings.ToUpper(levelNames[level])
+	}
+	return 9, "INFO"
+}
+
+// encodeProto 编码成 protobuf
+func (o *otlpEncoder) encodeProto(l *Log, batch []*httpMessage) {
+	// ExportLogsServiceRequest.resource_logs
+	l.b = pbAppendMessage(l.b, 1, func(b []byte) []byte {
+		// ResourceLogs.resource
+		b = pbAppendMessage(b, 1, func(b []byte) []byte {
+			for i := range o.resource {
+				b = pbAppendKeyValue(b, 1, &o.resource[i])
+			}
+			return b
+		})
+		for _, s := range otlpScopes(batch) {
+			// ResourceLogs.scope_logs
+			b = pbAppendMessage(b, 2, func(b []byte) []byte {
+				// ScopeLogs.scope
+				b = pbAppendMessage(b, 1, func(b []byte) []byte {
+					return pbAppendString(b, 1, s.name)
+				})
+				for _, m := range s.records {
+					// ScopeLogs.log_records
+					b = pbAppendMessage(b, 2, func(b []byte) []byte {
+						return o.appendProtoRecord(b, m)
+					})
+				}
+				return b
+			})
+		}
+		return b
+	})
+}
+
+// appendProtoRecord 编码一个 LogRecord
+func (o *otlpEncoder) appendProtoRecord(b []byte, m *httpMessage) []byte {
+	ns := uint64(m.time.UnixNano())
+	severity, text := otlpSeverity(m.level)
+	// time_unix_nano
+	b = pbAppendTag(b, 1, 1)
+	b = binary.LittleEndian.AppendUint64(b, ns)
+	// severity_number
+	b = pbAppendTag(b, 2, 0)
+	b = binary.AppendUvarint(b, uint64(severity))
+	// severity_text
+	b = pbAppendString(b, 3, text)
+	// body
+	b = pbAppendMessage(b, 5, func(b []byte) []byte {
+		b = pbAppendTag(b, 1, 2)
+		b = binary.AppendUvarint(b, uint64(len(m.line)))
+		return append(b, m.line...)
+	})
+	// attributes
+	traceID, spanID, ok := parseTraceContext(m.trace)
+	attrs := o.attributes(m, ok)
+	for i := range attrs {
+		b = pbAppendKeyValue(b, 6, &attrs[i])
+	}
+	// trace_id span_id
+	if ok {
+		b = pbAppendTag(b, 9, 2)
+		b = binary.AppendUvarint(b, uint64(len(traceID)))
+		b = append(b, traceID...)
+		if spanID != nil {
+			b = pbAppendTag(b, 10, 2)
+			b = binary.AppendUvarint(b, uint64(len(spanID)))
+			b = append(b, spanID...)
+		}
+	}
+	// observed_time_unix_nano
+	b = pbAppendTag(b, 11, 1)
+	b = binary.LittleEndian.AppendUint64(b, ns)
+	return b
+}
+
+// pbAppendTag 写入字段的 tag ，wire 是 0 varint ，1 fixed64 ，2 bytes
+func pbAppendTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wire))
+}
+
+// pbAppendString 写入字符串字段
+func pbAppendString(b []byte, field int, s string) []byte {
+	b = pbAppendTag(b, field, 2)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// pbAppendMessage 写入嵌套的消息，先写入内容，再在前面插入长度
+func pbAppendMessage(b []byte, field int, f func([]byte) []byte) []byte {
+	b = pbAppendTag(b, field, 2)
+	start := len(b)
+	b = f(b)
+	n := len(b) - start
+	var size [binary.MaxVarintLen64]byte
+	k := binary.PutUvarint(size[:], uint64(n))
+	b = append(b, size[:k]...)
+	copy(b[start+k:], b[start:start+n])
+	copy(b[start:], size[:k])
+	return b
+}
+
+// pbAppendKeyValue 写入 KeyValue
+func pbAppendKeyValue(b []byte, field int, f *Field) []byte {
+	return pbAppendMessage(b, field, func(b []byte) []byte {
+		b = pbAppendString(b, 1, f.Key)
+		// AnyValue
+		return pbAppendMessage(b, 2, func(b []byte) []byte {
+			switch f.Type {
+			case StringType:
+				return pbAppendString(b, 1, f.Str)
+			case BoolType:
+				b = pbAppendTag(b, 2, 0)
+				return binary.AppendUvarint(b, uint64(f.Int))
+			case IntType, UintType:
+				b = pbAppendTag(b, 3, 0)
+				return binary.AppendUvarint(b, uint64(f.Int))
+			case FloatType:
+				b = pbAppendTag(b, 4, 1)
+				return binary.LittleEndian.AppendUint64(b, uint64(f.Int))
+			}
+			return pbAppendString(b, 1, otlpString(f))
+		})
+	})
+}
+
+// otlpString 返回其他类型的字段的字符串
+func otlpString(f *Field) string {
+	switch f.Type {
+	case DurationType:
+		return time.Duration(f.Int).String()
+	case TimeType:
+		return f.TimeValue().Format(fieldTimeLayout)
+	}
+	return fmt.Sprint(f.Value())
+}
+
+// encodeJSON 编码成 OTLP/JSON
+func (o *otlpEncoder) encodeJSON(l *Log, batch []*httpMessage) {
+	l.b = append(l.b, `{"resourceLogs":[{"resource":{"attributes":`...)
+	o.appendJSONAttributes(l, o.resource)
+	l.b = append(l.b, `},"scopeLogs":[`...)
+	for i, s := range otlpScopes(batch) {
+		if i > 0 {
+			l.b = append(l.b, ',')
+		}
+		l.b = append(l.b, `{"scope":{"name":`...)
+		l.JSONString(s.name)
+		l.b = append(l.b, `},"logRecords":[`...)
+		for j, m := range s.records {
+			if j > 0 {
+				l.b = append(l.b, ',')
+			}
+			o.appendJSONRecord(l, m)
+		}
+		l.b = append(l.b, "]}"...)
+	}
+	l.b = append(l.b, "]}]}"...)
+}
+
+// appendJSONRecord 编码一个 LogRecord ，64 位整数是字符串，id 是十六进制
+func (o *otlpEncoder) appendJSONRecord(l *Log, m *httpMessage) {
+	ns := strconv.FormatInt(m.time.UnixNano(), 10)
+	severity, text := otlpSeverity(m.level)
+	l.b = append(l.b, `{"timeUnixNano":"`...)
+	l.b = append(l.b, ns...)
+	l.b = append(l.b, `","observedTimeUnixNano":"`...)
+	l.b = append(l.b, ns...)
+	l.b = append(l.b, `","severityNumber":`...)
+	l.b = strconv.AppendInt(l.b, int64(severity), 10)
+	l.b = append(l.b, `,"severityText":"`...)
+	l.b = append(l.b, text...)
+	l.b = append(l.b, `","body":{"stringValue":`...)
+	l.JSONBytes(m.line)
+	l.b = append(l.b, `},"attributes":`...)
+	traceID, spanID, ok := parseTraceContext(m.trace)
+	o.appendJSONAttributes(l, o.attributes(m, ok))
+	if ok {
+		l.b = append(l.b, `,"traceId":"`...)
+		l.b = append(l.b, hex.EncodeToString(traceID)...)
+		l.b = append(l.b, '"')
+		if spanID != nil {
+			l.b = append(l.b, `,"spanId":"`...)
+			l.b = append(l.b, hex.EncodeToString(spanID)...)
+			l.b = append(l.b, '"')
+		}
+	}
+	l.b = append(l.b, '}')
+}
+
+// appendJSONAttributes 编码 KeyValue 数组
+func (o *otlpEncoder) appendJSONAttributes(l *Log, attrs []Field) {
+	l.b = append(l.b, '[')
+	for i := range attrs {
+		f := &attrs[i]
+		if i > 0 {
+			l.b = append(l.b, ',')
+		}
+		l.b = append(l.b, `{"key":`...)
+		l.JSONString(f.Key)
+		l.b = append(l.b, `,"value":{`...)
+		switch f.Type {
+		case StringType:
+			l.b = append(l.b, `"stringValue":`...)
+			l.JSONString(f.Str)
+		case BoolType:
+			l.b = append(l.b, `"boolValue":`...)
+			l.b = strconv.AppendBool(l.b, f.Int == 1)
+		case IntType:
+			l.b = append(l.b, `"intValue":"`...)
+			l.b = strconv.AppendInt(l.b, f.Int, 10)
+			l.b = append(l.b, '"')
+		case UintType:
+			l.b = append(l.b, `"intValue":"`...)
+			l.b = strconv.AppendInt(l.b, f.Int, 10)
+			l.b = append(l.b, '"')
+		case FloatType:
+			v := math.Float64frombits(uint64(f.Int))
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				// JSON 没有这些值
+				l.b = append(l.b, `"stringValue":`...)
+				l.JSONString(strconv.FormatFloat(v, 'g', -1, 64))
+			} else {
+				l.b = append(l.b, `"doubleValue":`...)
+				l.b = strconv.AppendFloat(l.b, v, 'g', -1, 64)
+			}
+		default:
+			l.b = append(l.b, `"stringValue":`...)
+			l.JSONString(otlpString(f))
+		}
+		l.b = append(l.b, "}}"...)
+	}
+	l.b = append(l.b, ']')
+}
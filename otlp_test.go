@@ -0,0 +1,241 @@
+package log
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pbMessage 是解码后的 protobuf 消息，字段号 -> 值，
+// varint 和 fixed64 是 uint64 ，bytes 是 []byte
+type pbMessage map[int][]any
+
+// parsePB 解码 protobuf 消息
+func parsePB(t *testing.T, b []byte) pbMessage {
+	m := make(pbMessage)
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("bad tag")
+		}
+		b = b[n:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case 0:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatal("bad varint")
+			}
+			b = b[n:]
+			m[field] = append(m[field], v)
+		case 1:
+			m[field] = append(m[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || int(size) > len(b[n:]) {
+				t.Fatal("bad bytes")
+			}
+			m[field] = append(m[field], b[n:n+int(size)])
+			b = b[n+int(size):]
+		default:
+			t.Fatal("bad wire type")
+		}
+	}
+	return m
+}
+
+// msg 返回嵌套的消息
+func (m pbMessage) msg(t *testing.T, field, i int) pbMessage {
+	return parsePB(t, m[field][i].([]byte))
+}
+
+// str 返回字符串字段
+func (m pbMessage) str(field int) string {
+	if len(m[field]) < 1 {
+		return ""
+	}
+	return string(m[field][0].([]byte))
+}
+
+// attrs 返回 KeyValue 字段的值
+func (m pbMessage) attrs(t *testing.T, field int) map[string]any {
+	attrs := make(map[string]any)
+	for i := range m[field] {
+		kv := m.msg(t, field, i)
+		v := kv.msg(t, 2, 0)
+		switch {
+		case v[1] != nil:
+			attrs[kv.str(1)] = v.str(1)
+		case v[2] != nil:
+			attrs[kv.str(1)] = v[2][0].(uint64) == 1
+		case v[3] != nil:
+			attrs[kv.str(1)] = int64(v[3][0].(uint64))
+		case v[4] != nil:
+			attrs[kv.str(1)] = math.Float64frombits(v[4][0].(uint64))
+		}
+	}
+	return attrs
+}
+
+func Test_OTLPProto(t *testing.T) {
+	now := Now
+	defer func() { Now = now }()
+	Now = func() time.Time {
+		return time.Unix(1, 2)
+	}
+	s := newTestHTTPServer(t)
+	defer s.Close()
+	o, err := NewOTLP(&OTLPConfig{
+		URL:                s.URL,
+		ServiceName:        "svc",
+		ResourceAttributes: map[string]string{"env": "test"},
+		Caller:             true,
+		Gzip:               true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := NewLogger(o, DefaultHeader, "db").With(String("k", "v"))
+	traceID := "0af7651916cd43dd8448eb211c80319c"
+	spanID := "b7ad6b7169203331"
+	line := nextLine()
+	lg.ErrorFieldsTrace("00-"+traceID+"-"+spanID+"-01", "query", Int("n", -1), Bool("ok", true), Float64("f", 1.5))
+	lg.Info("plain")
+	o.Close()
+	bodies := s.Bodies()
+	if len(bodies) != 1 {
+		t.Fatal(bodies)
+	}
+	req := parsePB(t, []byte(bodies[0]))
+	rl := req.msg(t, 1, 0)
+	resource := rl.msg(t, 1, 0).attrs(t, 1)
+	if resource["service.name"] != "svc" || resource["env"] != "test" {
+		t.Fatal(resource)
+	}
+	sl := rl.msg(t, 2, 0)
+	if sl.msg(t, 1, 0).str(1) != "db" || len(sl[2]) != 2 {
+		t.Fatal(sl)
+	}
+	r := sl.msg(t, 2, 0)
+	if r[1][0].(uint64) != 1000000002 || r[11][0].(uint64) != 1000000002 ||
+		r[2][0].(uint64) != 17 || r.str(3) != "ERROR" ||
+		r.msg(t, 5, 0).str(1) != "query" ||
+		hex.EncodeToString(r[9][0].([]byte)) != traceID ||
+		hex.EncodeToString(r[10][0].([]byte)) != spanID {
+		t.Fatal(r)
+	}
+	attrs := r.attrs(t, 6)
+	if attrs["k"] != "v" || attrs["n"] != int64(-1) || attrs["ok"] != true || attrs["f"] != 1.5 ||
+		attrs["code.lineno"] != int64(line) || attrs["code.function"] != "github.com/qq51529210/log.Test_OTLPProto" {
+		t.Fatal(attrs)
+	}
+	r = sl.msg(t, 2, 1)
+	if r[2][0].(uint64) != 9 || r.str(3) != "INFO" || r.msg(t, 5, 0).str(1) != "plain" || r[9] != nil {
+		t.Fatal(r)
+	}
+}
+
+func Test_OTLPJSON(t *testing.T) {
+	s := newTestHTTPServer(t)
+	defer s.Close()
+	o, err := NewOTLP(&OTLPConfig{
+		URL:      s.URL,
+		Encoding: "json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := NewLogger(o, nil, "")
+	lg.WarnTrace("not-a-trace-id", "hello")
+	o.Write([]byte("[E] line\n"))
+	o.Close()
+	bodies := s.Bodies()
+	if len(bodies) != 1 {
+		t.Fatal(bodies)
+	}
+	type anyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	var req struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				LogRecords []struct {
+					TimeUnixNano   string   `json:"timeUnixNano"`
+					SeverityNumber int      `json:"severityNumber"`
+					SeverityText   string   `json:"severityText"`
+					Body           anyValue `json:"body"`
+					Attributes     []struct {
+						Key   string   `json:"key"`
+						Value anyValue `json:"value"`
+					} `json:"attributes"`
+					TraceID string `json:"traceId"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	err = json.Unmarshal([]byte(bodies[0]), &req)
+	if err != nil {
+		t.Fatal(err, bodies[0])
+	}
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatal(bodies[0])
+	}
+	r := records[0]
+	if r.SeverityNumber != 13 || r.SeverityText != "WARN" || r.Body.StringValue != "hello" ||
+		r.TraceID != "" || len(r.Attributes) != 1 ||
+		r.Attributes[0].Key != "trace" || r.Attributes[0].Value.StringValue != "not-a-trace-id" {
+		t.Fatal(bodies[0])
+	}
+	r = records[1]
+	if r.SeverityNumber != 17 || r.Body.StringValue != "[E] line" || r.TimeUnixNano == "" {
+		t.Fatal(bodies[0])
+	}
+}
+
+func Test_OTLPFieldCopy(t *testing.T) {
+	s := newTestHTTPServer(t)
+	defer s.Close()
+	o, err := NewOTLP(&OTLPConfig{
+		URL:      s.URL,
+		Encoding: "json",
+		Linger:   10000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := NewLogger(o, nil, "")
+	v := &struct{ N int }{1}
+	lg.InfoFields("a", Any("v", v))
+	// 发送之前修改
+	v.N = 2
+	o.Close()
+	bodies := s.Bodies()
+	if len(bodies) != 1 || !strings.Contains(bodies[0], `{"key":"v","value":{"stringValue":"&{1}"}}`) {
+		t.Fatal(bodies)
+	}
+}
+
+func Test_ParseTraceContext(t *testing.T) {
+	for _, c := range []struct {
+		s          string
+		trace, spn string
+		ok         bool
+	}{
+		{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", true},
+		{"0af7651916cd43dd8448eb211c80319c", "0af7651916cd43dd8448eb211c80319c", "", true},
+		{"0af7651916cd43dd8448eb211c80319c:b7ad6b7169203331", "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", true},
+		{"00000000000000000000000000000000", "", "", false},
+		{"abc", "", "", false},
+	} {
+		trace, span, ok := parseTraceContext(c.s)
+		if ok != c.ok || hex.EncodeToString(trace) != c.trace || hex.EncodeToString(span) != c.spn {
+			t.Fatal(c.s)
+		}
+	}
+}
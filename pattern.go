@@ -0,0 +1,226 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// AppName 是 {app} 输出的应用名称，默认是执行文件的名称
+	AppName = filepath.Base(os.Args[0])
+)
+
+// headerPattern 是编译后的模板
+type headerPattern struct {
+	// 按顺序写入
+	appenders []func(log *Log)
+	// 是否需要调用者的信息
+	frame bool
+}
+
+// header 是 FormatHeader
+func (p *headerPattern) header(log *Log, depth int) {
+	if p.frame {
+		log.frame = log.callerFrame(depth)
+	}
+	for _, a := range p.appenders {
+		a(log)
+	}
+	log.frame = nil
+}
+
+// NewHeader 将模板编译成 FormatHeader ，比如 "{time:rfc3339} {level} {name} {file}:{line} {trace}" ，
+// 支持的占位符有
+//
+//	{time} {time:layout|zone|precision} 时间，参数和 TimeConfig 相同，没有参数使用 FormatTime
+//	{level} {level:upper} {level:short} 级别，info/INFO/I
+//	{name} Logger 的名称
+//	{trace} 追踪
+//	{file} {path} {line} 调用者的文件名，完整路径和行号
+//	{func} {pkg} 调用者的函数名，相对于 ModulePath 的包路径
+//	{goid} goroutine ID
+//	{host} {pid} {app} 主机名，进程 ID 和 AppName
+//
+// "{{" 和 "}}" 是 "{" 和 "}" 。
+// 名称，级别和追踪在 TextEncoder 中还会输出，所以模板中有的话，使用 HeaderEncoder 。
+func NewHeader(pattern string) (FormatHeader, error) {
+	p := new(headerPattern)
+	var text []byte
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '{':
+			if i+1 < len(pattern) && pattern[i+1] == '{' {
+				text = append(text, c)
+				i++
+				continue
+			}
+			j := strings.IndexByte(pattern[i:], '}')
+			if j < 0 {
+				return nil, fmt.Errorf("header pattern %q: unclosed {", pattern)
+			}
+			// 前面的文本
+			if len(text) > 0 {
+				p.appendText(string(text))
+				text = text[:0]
+			}
+			if err := p.appendToken(pattern[i+1 : i+j]); err != nil {
+				return nil, fmt.Errorf("header pattern %q: %w", pattern, err)
+			}
+			i += j
+		case '}':
+			if i+1 < len(pattern) && pattern[i+1] == '}' {
+				i++
+			}
+			text = append(text, c)
+		default:
+			text = append(text, c)
+		}
+	}
+	if len(text) > 0 {
+		p.appendText(string(text))
+	}
+	return p.header, nil
+}
+
+// appendText 添加文本
+func (p *headerPattern) appendText(s string) {
+	p.appenders = append(p.appenders, func(log *Log) {
+		log.b = append(log.b, s...)
+	})
+}
+
+// appendToken 添加占位符
+func (p *headerPattern) appendToken(token string) error {
+	name, arg, _ := strings.Cut(token, ":")
+	switch name {
+	case "time":
+		if arg == "" {
+			p.appenders = append(p.appenders, FormatTime)
+			return nil
+		}
+		var conf TimeConfig
+		args := strings.Split(arg, "|")
+		conf.Layout = args[0]
+		if len(args) > 1 {
+			conf.Zone = args[1]
+		}
+		if len(args) > 2 {
+			conf.Precision = args[2]
+		}
+		tf, err := NewTimeFormatter(&conf)
+		if err != nil {
+			return err
+		}
+		p.appenders = append(p.appenders, tf.Format)
+	case "level":
+		switch arg {
+		case "":
+			p.appenders = append(p.appenders, func(log *Log) {
+				if log.e != nil {
+					log.b = append(log.b, log.e.Level.String()...)
+				}
+			})
+		case "upper":
+			p.appenders = append(p.appenders, func(log *Log) {
+				if log.e != nil {
+					log.b = append(log.b, strings.ToUpper(log.e.Level.String())...)
+				}
+			})
+		case "short":
+			p.appenders = append(p.appenders, func(log *Log) {
+				if log.e != nil {
+					log.b = append(log.b, levels[log.e.Level][1])
+				}
+			})
+		default:
+			return fmt.Errorf("unknown level format %q", arg)
+		}
+	case "name":
+		p.appenders = append(p.appenders, func(log *Log) {
+			if log.e != nil {
+				log.b = append(log.b, log.e.Name...)
+			}
+		})
+	case "trace":
+		p.appenders = append(p.appenders, func(log *Log) {
+			if log.e != nil {
+				log.b = append(log.b, log.e.Trace...)
+			}
+		})
+	case "file":
+		p.frame = true
+		p.appenders = append(p.appenders, func(log *Log) {
+			log.b = append(log.b, trimPath(log.frame.File)...)
+		})
+	case "path":
+		p.frame = true
+		p.appenders = append(p.appenders, func(log *Log) {
+			log.b = append(log.b, log.frame.File...)
+		})
+	case "line":
+		p.frame = true
+		p.appenders = append(p.appenders, func(log *Log) {
+			log.Int(log.frame.Line)
+		})
+	case "func":
+		p.frame = true
+		p.appenders = append(p.appenders, func(log *Log) {
+			log.b = append(log.b, log.frame.Func...)
+		})
+	case "pkg":
+		p.frame = true
+		p.appenders = append(p.appenders, func(log *Log) {
+			log.b = append(log.b, trimPackage(log.frame.Package)...)
+		})
+	case "goid":
+		p.appenders = append(p.appenders, func(log *Log) {
+			log.Int(goroutineID())
+		})
+	case "host":
+		host, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		p.appendText(host)
+	case "pid":
+		p.appendText(strconv.Itoa(os.Getpid()))
+	case "app":
+		p.appenders = append(p.appenders, func(log *Log) {
+			log.b = append(log.b, AppName...)
+		})
+	default:
+		return fmt.Errorf("unknown token %q", token)
+	}
+	return nil
+}
+
+// HeaderEncoder 只输出 "Header text k=v" ，名称，级别和追踪由 NewHeader 的模板决定
+type HeaderEncoder struct{}
+
+// Encode 实现 Encoder
+func (HeaderEncoder) Encode(l *Log, e *Entry) {
+	if e.Header != nil {
+		e.Header(l, e.Depth+1)
+		l.b = append(l.b, ' ')
+	}
+	// 日志
+	l.b = append(l.b, e.Message...)
+	// 字段
+	if e.BoundEncoded != nil {
+		l.b = append(l.b, e.BoundEncoded...)
+	} else {
+		l.Fields(e.Bound)
+	}
+	l.Fields(e.Fields)
+	// 换行
+	l.b = append(l.b, '\n')
+}
+
+// EncodeFields 实现 FieldsEncoder
+func (HeaderEncoder) EncodeFields(l *Log, fields []Field) {
+	l.Fields(fields)
+}
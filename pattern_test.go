@@ -0,0 +1,47 @@
+package log
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_NewHeader(t *testing.T) {
+	now := Now
+	defer func() { Now = now }()
+	Now = func() time.Time {
+		return time.Date(2023, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	}
+	header, err := NewHeader("{time:rfc3339|utc|ms} {level:upper} {name} {file}:{line} {func} {trace} {pid} {{x}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var l Log
+	lg := NewLogger(&l, header, "app")
+	lg.Encoder = HeaderEncoder{}
+	line := nextLine()
+	lg.WarnTrace("t1", "hello")
+	s := "2023-01-02T03:04:05.006Z WARN app pattern_test.go:" + strconv.Itoa(line) + " Test_NewHeader t1 " + strconv.Itoa(os.Getpid()) + " {x} hello\n"
+	if string(l.b) != s {
+		t.Fatal(string(l.b))
+	}
+	// TextEncoder
+	header, err = NewHeader("{level:short}|{goid}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Reset()
+	lg = NewLogger(&l, header, "")
+	lg.Error("a")
+	if !strings.HasPrefix(string(l.b), "[E] E|") || !strings.HasSuffix(string(l.b), " a\n") {
+		t.Fatal(string(l.b))
+	}
+	// 错误
+	for _, p := range []string{"{time", "{unknown}", "{level:x}", "{time:rfc3339|no/zone}"} {
+		if _, err := NewHeader(p); err == nil {
+			t.Fatal(p)
+		}
+	}
+}
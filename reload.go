@@ -0,0 +1,333 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// loggerState 是可以替换的头格式和编码
+type loggerState struct {
+	header  FormatHeader
+	encoder Encoder
+}
+
+// swapEncoder 是 Loggers 创建的 Logger 的 Encoder ，
+// 使用当前的头格式和编码，可以并发替换
+type swapEncoder struct {
+	state atomic.Pointer[loggerState]
+}
+
+// Encode 实现 Encoder
+func (s *swapEncoder) Encode(l *Log, e *Entry) {
+	st := s.state.Load()
+	// 下一层的 Encode 不能再使用 depth
+	if e.PC == 0 {
+		e.PC = e.CallerPC()
+	}
+	l.pc = e.PC
+	e.Header = st.header
+	st.encoder.Encode(l, e)
+}
+
+// swapWriter 是 Loggers 创建的 Logger 的输出，可以并发替换
+type swapWriter struct {
+	lock sync.RWMutex
+	w    io.Writer
+}
+
+// Write 实现 io.Writer
+func (s *swapWriter) Write(b []byte) (int, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.w.Write(b)
+}
+
+// WriteEntry 实现 EntryWriter
+func (s *swapWriter) WriteEntry(e *Entry, b []byte) (int, error) {
+	// 下一层的 WriteEntry 不能再使用 depth
+	if e.PC == 0 {
+		e.PC = e.CallerPC()
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if w, ok := s.w.(EntryWriter); ok {
+		return w.WriteEntry(e, b)
+	}
+	return s.w.Write(b)
+}
+
+// Flush 实现 Flusher
+func (s *swapWriter) Flush() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if f, ok := s.w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// swap 替换输出，返回之后，旧的输出没有正在进行的写入
+func (s *swapWriter) swap(w io.Writer) {
+	s.lock.Lock()
+	s.w = w
+	s.lock.Unlock()
+}
+
+// managedLogger 是 Loggers 管理的一个 Logger
+type managedLogger struct {
+	lg *Logger
+	// 使用的配置
+	conf *LoggerConfig
+	// 输出
+	w *swapWriter
+	// 头格式和编码
+	enc *swapEncoder
+	// 创建的输出
+	outputs []io.Writer
+}
+
+// Loggers 是 NewLoggers 创建的 Logger 。
+// 重新加载配置的时候，已经创建的 Logger 的级别，头格式，编码和输出会被替换，
+// 调用者持有的 Logger 和它的子 Logger 不需要重新获取。
+type Loggers struct {
+	lock sync.Mutex
+	// 读取环境变量
+	lookupEnv func(string) (string, bool)
+	// 默认的
+	def *managedLogger
+	// 名称
+	loggers map[string]*managedLogger
+}
+
+// loggerChange 是重新加载的时候，一个 Logger 的修改
+type loggerChange struct {
+	conf  *LoggerConfig
+	level Level
+	state *loggerState
+	// 新的输出，nil 表示不修改
+	w       io.Writer
+	outputs []io.Writer
+}
+
+// Reload 使用 conf 重新加载，配置有错误的时候不做任何修改。
+// 输出的配置修改了的 Logger ，会创建新的输出，替换之后关闭旧的输出，
+// 关闭之前旧的输出会写完已有的日志。
+// 配置中没有的 Logger 保持不变，新的 Logger 会被创建。
+// 默认的 Logger 不能修改，因为 SetLogger 不能和包函数并发调用。
+func (ls *Loggers) Reload(conf *Config) error {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	conf = conf.withEnv(ls.lookupEnv)
+	err := validateConfig(conf)
+	if err != nil {
+		return err
+	}
+	// 准备所有的修改
+	changes := make([]*loggerChange, 0, len(conf.Loggers))
+	names := make(map[string]int)
+	fail := func(err error) error {
+		for _, c := range changes {
+			closeOutputs(c.outputs)
+		}
+		return err
+	}
+	for i, lc := range conf.Loggers {
+		if _, ok := names[lc.Name]; ok {
+			return fail(fmt.Errorf("loggers[%d].name: duplicate %q", i, lc.Name))
+		}
+		names[lc.Name] = i
+		c := &loggerChange{conf: lc}
+		c.level, c.state, err = newLoggerState(lc)
+		if err != nil {
+			return fail(fmt.Errorf("loggers[%d]%w", i, err))
+		}
+		m := ls.loggers[lc.Name]
+		if m == nil || !reflect.DeepEqual(m.conf.Outputs, lc.Outputs) {
+			c.w, c.outputs, err = newOutputs(lc.Outputs)
+			if err != nil {
+				return fail(fmt.Errorf("loggers[%d]%w", i, err))
+			}
+		}
+		changes = append(changes, c)
+	}
+	def := changes[0]
+	if conf.Default != "" {
+		i, ok := names[conf.Default]
+		if !ok {
+			return fail(fmt.Errorf("default: logger %q not found", conf.Default))
+		}
+		def = changes[i]
+	}
+	// SetLogger 修改包函数不是并发安全的，所以不能修改默认的
+	if ls.def != nil && ls.def.conf.Name != def.conf.Name {
+		return fail(fmt.Errorf("default: can not change from %q to %q", ls.def.conf.Name, def.conf.Name))
+	}
+	// 应用
+	var errs []error
+	for _, c := range changes {
+		m := ls.loggers[c.conf.Name]
+		if m == nil {
+			m = &managedLogger{
+				w:   new(swapWriter),
+				enc: new(swapEncoder),
+			}
+			m.lg = NewLogger(m.w, nil, c.conf.Name)
+			m.lg.Encoder = m.enc
+			ls.loggers[c.conf.Name] = m
+		}
+		m.enc.state.Store(c.state)
+		m.lg.SetLevel(c.level)
+		if c.w != nil {
+			m.w.swap(c.w)
+			errs = append(errs, closeOutputs(m.outputs))
+			m.outputs = c.outputs
+		}
+		m.conf = c.conf
+	}
+	if ls.def == nil {
+		ls.def = ls.loggers[def.conf.Name]
+		SetLogger(ls.def.lg)
+	}
+	return errors.Join(errs...)
+}
+
+// Logger 返回名称是 name 的 Logger ，没有返回 nil
+func (ls *Loggers) Logger(name string) *Logger {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	if m := ls.loggers[name]; m != nil {
+		return m.lg
+	}
+	return nil
+}
+
+// Default 返回默认的 Logger
+func (ls *Loggers) Default() *Logger {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	return ls.def.lg
+}
+
+// Flush 同步所有的输出
+func (ls *Loggers) Flush() error {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	var errs []error
+	for _, m := range ls.loggers {
+		errs = append(errs, m.w.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭所有的输出，不关闭 os.Stdout 和 os.Stderr
+func (ls *Loggers) Close() error {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+	var errs []error
+	for _, m := range ls.loggers {
+		errs = append(errs, closeOutputs(m.outputs))
+		m.outputs = nil
+	}
+	return errors.Join(errs...)
+}
+
+// WatchConfig 是 Loggers.Watch 的参数
+type WatchConfig struct {
+	// 配置文件的路径
+	Path string `json:"path" yaml:"path" validate:"required"`
+	// 检查文件修改的时间间隔，单位毫秒，0 不检查
+	Interval int `json:"interval" yaml:"interval" validate:"omitempty,min=10"`
+	// 收到 SIGHUP 的时候是否重新加载
+	Signal bool `json:"signal" yaml:"signal"`
+	// 读取配置文件，nil 使用 LoadConfig
+	Load func(path string) (*Config, error) `json:"-" yaml:"-"`
+}
+
+// Watcher 是 Loggers.Watch 返回的监视协程
+type Watcher struct {
+	wait sync.WaitGroup
+	// 退出协程通知
+	exit chan struct{}
+	// 停止一次
+	once sync.Once
+}
+
+// Watch 启动一个协程，配置文件的修改时间或者大小变化，或者收到 SIGHUP 的时候，
+// 读取配置文件调用 Reload ，错误输出到 os.Stderr 。
+func (ls *Loggers) Watch(conf *WatchConfig) *Watcher {
+	w := new(Watcher)
+	w.exit = make(chan struct{})
+	load := conf.Load
+	if load == nil {
+		load = LoadConfig
+	}
+	// 定时器
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if conf.Interval > 0 {
+		ticker = time.NewTicker(time.Duration(conf.Interval) * time.Millisecond)
+		tick = ticker.C
+	}
+	// 信号
+	var sig chan os.Signal
+	if conf.Signal {
+		sig = make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP)
+	}
+	last, _ := os.Stat(conf.Path)
+	reload := func() {
+		c, err := load(conf.Path)
+		if err == nil {
+			err = ls.Reload(c)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	w.wait.Add(1)
+	go func() {
+		defer w.wait.Done()
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+		if sig != nil {
+			defer signal.Stop(sig)
+		}
+		for {
+			select {
+			case <-w.exit:
+				return
+			case <-sig:
+				last, _ = os.Stat(conf.Path)
+				reload()
+			case <-tick:
+				info, err := os.Stat(conf.Path)
+				if err != nil {
+					continue
+				}
+				if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+					continue
+				}
+				last = info
+				reload()
+			}
+		}
+	}()
+	return w
+}
+
+// Stop 停止监视，等待协程退出
+func (w *Watcher) Stop() {
+	w.once.Do(func() {
+		close(w.exit)
+	})
+	w.wait.Wait()
+}
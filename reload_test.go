@@ -0,0 +1,151 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nextLine 返回调用者的下一行的行号
+func nextLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line + 1
+}
+
+// readDir 返回目录中所有文件的内容
+func readDir(t *testing.T, dir string) string {
+	var data []byte
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			d, _ := os.ReadFile(path)
+			data = append(data, d...)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func Test_LoggersReload(t *testing.T) {
+	defaultLogger := DefaultLogger
+	defer SetLogger(defaultLogger)
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	newConf := func(level, dir string) *Config {
+		return &Config{
+			Loggers: []*LoggerConfig{{
+				Name:    "app",
+				Level:   level,
+				Header:  "{level:short} {file}:{line}",
+				Encoder: "header",
+				Outputs: []*OutputConfig{{
+					Type: "file",
+					File: &FileConfig{RootDir: dir, MaxFileSize: "1M", MaxKeepDay: 1, SyncInterval: 10},
+				}},
+			}},
+		}
+	}
+	ls, err := NewLoggers(newConf("info", dir1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.Close()
+	lg := ls.Logger("app").With(String("k", "v"))
+	// 并发写入的时候重新加载
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for i := 0; i < 100; i++ {
+			lg.Info("loop")
+		}
+	}()
+	lg.Debug("debug")
+	line1 := nextLine()
+	lg.Info("1")
+	// 只修改级别，不重新创建输出
+	err = ls.Reload(newConf("debug", dir1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line2 := nextLine()
+	lg.Debug("2")
+	// 修改输出
+	err = ls.Reload(newConf("warn", dir2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait.Wait()
+	lg.Info("info")
+	line3 := nextLine()
+	lg.Warn("3")
+	// 错误的配置不修改
+	if ls.Reload(newConf("x", dir1)) == nil || lg.Level() != WarnLevel {
+		t.FailNow()
+	}
+	// nil 的元素
+	if ls.Reload(&Config{Loggers: []*LoggerConfig{nil}}) == nil || lg.Level() != WarnLevel {
+		t.FailNow()
+	}
+	// 不能修改默认的
+	conf := newConf("warn", dir2)
+	conf.Loggers = append(conf.Loggers, &LoggerConfig{Name: "other"})
+	conf.Default = "other"
+	if ls.Reload(conf) == nil || ls.Logger("other") != nil || ls.Default() != ls.Logger("app") {
+		t.FailNow()
+	}
+	ls.Close()
+	// 旧的文件已经关闭
+	var lines []string
+	for _, line := range strings.SplitAfter(readDir(t, dir1), "\n") {
+		if !strings.HasSuffix(line, " loop k=v\n") {
+			lines = append(lines, line)
+		}
+	}
+	s := strings.Join(lines, "")
+	if s != fmt.Sprintf("I reload_test.go:%d 1 k=v\nD reload_test.go:%d 2 k=v\n", line1, line2) {
+		t.Fatalf("%q", s)
+	}
+	s = readDir(t, dir2)
+	if s != fmt.Sprintf("W reload_test.go:%d 3 k=v\n", line3) {
+		t.Fatalf("%q", s)
+	}
+}
+
+func Test_LoggersWatch(t *testing.T) {
+	defaultLogger := DefaultLogger
+	defer SetLogger(defaultLogger)
+	path := filepath.Join(t.TempDir(), "log.json")
+	writeConf := func(level string) {
+		d, _ := json.Marshal(&Config{Loggers: []*LoggerConfig{{Level: level}}})
+		if err := os.WriteFile(path, d, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConf("info")
+	conf, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := NewLoggers(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.Close()
+	w := ls.Watch(&WatchConfig{Path: path, Interval: 10})
+	defer w.Stop()
+	writeConf("error")
+	for i := 0; i < 100 && ls.Default().Level() != ErrorLevel; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ls.Default().Level() != ErrorLevel {
+		t.FailNow()
+	}
+}
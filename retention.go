@@ -0,0 +1,118 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// logFile 是磁盘上的一个日志文件
+type logFile struct {
+	path string
+	size int64
+	// 修改时间，UnixNano
+	time int64
+}
+
+// checkLimit 检查所有日志文件的总大小和数量，超过了从最旧的开始删除，
+// 当前打开的文件不会删除。
+func (f *File) checkLimit() {
+	if f.maxTotalSize < 1 && f.maxFiles < 1 {
+		return
+	}
+	// 当前的文件
+	f.lock.Lock()
+	cur := ""
+	if f.file != nil {
+		cur = f.file.Name()
+	}
+	f.lock.Unlock()
+	// 所有的文件
+	files, err := f.listFiles()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	// 从最旧的开始删除
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].time < files[j].time
+	})
+	count := len(files)
+	for _, file := range files {
+		if (f.maxTotalSize < 1 || total <= f.maxTotalSize) &&
+			(f.maxFiles < 1 || count <= f.maxFiles) {
+			break
+		}
+		if file.path == cur {
+			continue
+		}
+		err = os.Remove(file.path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		total -= file.size
+		count--
+		// 删除空的日期目录
+		if !f.flat {
+			os.Remove(filepath.Dir(file.path))
+		}
+	}
+}
+
+// listFiles 返回 root/date/ 或者 root/ 下所有的日志文件，不包括压缩中的临时文件
+func (f *File) listFiles() ([]*logFile, error) {
+	if f.flat {
+		return f.listDir(f.rootDir)
+	}
+	dirEntries, err := os.ReadDir(f.rootDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*logFile
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		dirFiles, err := f.listDir(filepath.Join(f.rootDir, dirEntry.Name()))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// listDir 返回 dir 下所有的日志文件，不包括压缩中的临时文件和其他的文件
+func (f *File) listDir(dir string) ([]*logFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*logFile
+	for _, entry := range entries {
+		if entry.IsDir() ||
+			strings.HasSuffix(entry.Name(), compressTempExt) ||
+			!f.namer.matchCompressed(entry.Name()) {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		files = append(files, &logFile{
+			path: filepath.Join(dir, fi.Name()),
+			size: fi.Size(),
+			time: fi.ModTime().UnixNano(),
+		})
+	}
+	return files, nil
+}
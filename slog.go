@@ -0,0 +1,155 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+const (
+	// slog 调用 Handler.Handle 时，调用者的深度，没有 pc 的时候使用
+	slogDepth = 4
+	// 默认的追踪键
+	defaultSlogTraceKey = "trace"
+)
+
+// SlogHandler 实现 slog.Handler ，使用 Logger 输出。
+// 分组的属性使用 "group.key" 作为字段的键，
+// 顶层的追踪属性输出到 Logger 的追踪位置。
+type SlogHandler struct {
+	lg *Logger
+	// 追踪属性的键
+	traceKey string
+	// WithAttrs 的追踪
+	trace string
+	// WithAttrs 的字段
+	fields []Field
+	// WithGroup 的前缀 "a.b."
+	group string
+}
+
+// NewSlogHandler 返回 SlogHandler ，traceKey 是追踪属性的键，空使用 "trace"
+func NewSlogHandler(lg *Logger, traceKey string) *SlogHandler {
+	if traceKey == "" {
+		traceKey = defaultSlogTraceKey
+	}
+	return &SlogHandler{
+		lg:       lg,
+		traceKey: traceKey,
+	}
+}
+
+// slogLevel 返回 slog.Level 对应的级别
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
+
+// Enabled 实现 slog.Handler
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.lg.Enabled(slogLevel(level))
+}
+
+// Handle 实现 slog.Handler ，ctx 中的追踪和字段使用 Logger 的 Extractor 提取
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	trace, ctxFields := h.lg.extract(ctx)
+	if h.trace != "" {
+		trace = h.trace
+	}
+	fields := make([]Field, 0, len(ctxFields)+len(h.fields)+r.NumAttrs())
+	fields = append(fields, ctxFields...)
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = h.appendAttr(fields, &trace, h.group, a)
+		return true
+	})
+	// 日志
+	m := logPool.Get().(*Log)
+	m.b = append(m.b[:0], r.Message...)
+	h.lg.output(slogDepth, r.PC, slogLevel(r.Level), trace, m.b, fields)
+	logPool.Put(m)
+	return nil
+}
+
+// WithAttrs 实现 slog.Handler
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) < 1 {
+		return h
+	}
+	hh := *h
+	hh.fields = make([]Field, 0, len(h.fields)+len(attrs))
+	hh.fields = append(hh.fields, h.fields...)
+	for _, a := range attrs {
+		hh.fields = hh.appendAttr(hh.fields, &hh.trace, hh.group, a)
+	}
+	return &hh
+}
+
+// WithGroup 实现 slog.Handler
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	hh := *h
+	hh.group = h.group + name + "."
+	return &hh
+}
+
+// appendAttr 将 a 转换成字段，添加到 fields
+func (h *SlogHandler) appendAttr(fields []Field, trace *string, group string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	// 忽略空的属性
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) < 1 {
+			return fields
+		}
+		// 键为空，属性放到当前的分组
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range attrs {
+			fields = h.appendAttr(fields, trace, group, ga)
+		}
+		return fields
+	case slog.KindString:
+		// 顶层的追踪
+		if group == "" && a.Key == h.traceKey {
+			*trace = v.String()
+			return fields
+		}
+	}
+	return append(fields, slogField(group+a.Key, v))
+}
+
+// slogField 返回 slog.Value 对应的字段
+func slogField(key string, v slog.Value) Field {
+	switch v.Kind() {
+	case slog.KindString:
+		return String(key, v.String())
+	case slog.KindInt64:
+		return Int64(key, v.Int64())
+	case slog.KindUint64:
+		return Uint64(key, v.Uint64())
+	case slog.KindFloat64:
+		return Float64(key, v.Float64())
+	case slog.KindBool:
+		return Bool(key, v.Bool())
+	case slog.KindDuration:
+		return Duration(key, v.Duration())
+	case slog.KindTime:
+		return Time(key, v.Time())
+	}
+	return Any(key, v.Any())
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_SlogHandler(t *testing.T) {
+	var buf strings.Builder
+	lg := NewLogger(&buf, FileNameHeader, "slog")
+	sl := slog.New(NewSlogHandler(lg, ""))
+	// 级别和调用者
+	sl.Info("hello", "a", 1, slog.Group("g", "b", true), "trace", "t1")
+	s := buf.String()
+	if !strings.HasPrefix(s, "[slog] [I] ") ||
+		!strings.Contains(s, " slog_test.go:") ||
+		!strings.HasSuffix(s, " [t1] hello a=1 g.b=true\n") {
+		t.Fatal(s)
+	}
+	// WithAttrs 和 WithGroup
+	buf.Reset()
+	sl.With("trace", "t2", "x", "y").WithGroup("req").With("id", 2).Warn("world", "c", "d")
+	s = buf.String()
+	if !strings.HasPrefix(s, "[slog] [W] ") ||
+		!strings.HasSuffix(s, " [t2] world x=y req.id=2 req.c=d\n") {
+		t.Fatal(s)
+	}
+	// 分组中的 trace 不是追踪
+	buf.Reset()
+	sl.WithGroup("g").Error("e", "trace", "t3")
+	s = buf.String()
+	if !strings.HasPrefix(s, "[slog] [E] ") ||
+		!strings.HasSuffix(s, " e g.trace=t3\n") {
+		t.Fatal(s)
+	}
+	// 级别
+	buf.Reset()
+	lg.DisableDebug = true
+	sl.Debug("debug")
+	if buf.Len() != 0 {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,349 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认的结构化数据 ID ，32473 是文档使用的企业编号
+	defaultSyslogSDID = "trace@32473"
+	// 默认的超时
+	defaultSyslogTimeout = 10 * time.Second
+)
+
+var (
+	errSyslogClosed = errors.New("syslog has been closed")
+	// 本地的 syslog socket
+	syslogLocalAddrs = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+	// 设施
+	syslogFacilities = map[string]int{
+		"kern": 0, "user": 1, "mail": 2, "daemon": 3,
+		"auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+		"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+		"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+		"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+	}
+	// 级别对应的严重性，debug/info/warn/error/panic/fatal
+	syslogSeverities = []int{7, 6, 4, 3, 2, 1}
+)
+
+// SyslogConfig 是 NewSyslog 的参数。
+type SyslogConfig struct {
+	// 网络，unix/unixgram/udp/tcp ，空的时候使用本地的 syslog
+	Network string `json:"network" yaml:"network" validate:"omitempty,oneof=unix unixgram udp tcp"`
+	// 地址，unix 是 socket 的路径，空的时候依次尝试 /dev/log ，/var/run/syslog 和 /var/run/log
+	Addr string `json:"addr" yaml:"addr"`
+	// 格式，rfc3164/rfc5424 ，默认是 rfc3164
+	Format string `json:"format" yaml:"format" validate:"omitempty,oneof=rfc3164 rfc5424"`
+	// 流式连接的分帧，octet 是 RFC 6587 的长度前缀，lf 是换行结尾，
+	// 默认 tcp 是 octet ，unix 是 lf
+	Framing string `json:"framing" yaml:"framing" validate:"omitempty,oneof=octet lf"`
+	// 设施，kern/user/mail/daemon/auth/syslog/lpr/news/uucp/cron/authpriv/ftp/local0-7 ，默认是 user
+	Facility string `json:"facility" yaml:"facility"`
+	// 应用名称，默认是 AppName
+	AppName string `json:"appName" yaml:"appName"`
+	// 主机名，默认是 os.Hostname
+	Hostname string `json:"hostname" yaml:"hostname"`
+	// rfc5424 中追踪的结构化数据 ID ，默认是 trace@32473
+	SDID string `json:"sdid" yaml:"sdid"`
+	// 连接和写入的超时，单位毫秒，默认是 10000
+	Timeout int `json:"timeout" yaml:"timeout" validate:"omitempty,min=1"`
+}
+
+// NewSyslog 返回一个 Syslog 实例，连接在第一次写入的时候创建。
+func NewSyslog(conf *SyslogConfig) (*Syslog, error) {
+	s := new(Syslog)
+	s.network = conf.Network
+	s.addr = conf.Addr
+	if s.network == "" && s.addr != "" {
+		return nil, errors.New("syslog network is empty")
+	}
+	if s.addr == "" && s.network != "" && s.network != "unix" && s.network != "unixgram" {
+		return nil, errors.New("syslog addr is empty")
+	}
+	s.rfc5424 = conf.Format == "rfc5424"
+	// 分帧
+	switch conf.Framing {
+	case "octet":
+		s.octet = true
+	case "":
+		s.octet = s.network == "tcp"
+	}
+	// 设施
+	facility := syslogFacilities["user"]
+	if conf.Facility != "" {
+		var ok bool
+		facility, ok = syslogFacilities[strings.ToLower(conf.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", conf.Facility)
+		}
+	}
+	s.facility = facility
+	s.appName = conf.AppName
+	if s.appName == "" {
+		s.appName = AppName
+	}
+	s.hostname = conf.Hostname
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	s.sdid = conf.SDID
+	if s.sdid == "" {
+		s.sdid = defaultSyslogSDID
+	}
+	s.pid = strconv.Itoa(os.Getpid())
+	s.timeout = time.Duration(conf.Timeout) * time.Millisecond
+	if s.timeout <= 0 {
+		s.timeout = defaultSyslogTimeout
+	}
+	return s, nil
+}
+
+// Syslog 实现了 io.Writer 和 EntryWriter 接口，可以作为 Logger 的输出。
+// 每一行日志是一条 syslog 消息，级别对应严重性，
+// Write 从 "[name] [level] " 前缀中解析级别，WriteEntry 使用 Entry 的级别，名称和追踪。
+// 写入失败的时候会重新连接，再写一次。
+type Syslog struct {
+	lock sync.Mutex
+	// 是否已关闭标志
+	closed bool
+	// 网络和地址
+	network string
+	addr    string
+	// 当前的连接
+	conn net.Conn
+	// 当前连接是否是流式的
+	stream bool
+	// rfc5424 格式
+	rfc5424 bool
+	// 流式连接使用长度前缀
+	octet bool
+	// 消息头
+	facility int
+	appName  string
+	hostname string
+	pid      string
+	sdid     string
+	// 超时
+	timeout time.Duration
+	// 编码缓存
+	buf []byte
+}
+
+// Write 实现 io.Writer 。
+func (s *Syslog) Write(b []byte) (int, error) {
+	return s.write(syslogLevel(b), "", "", b)
+}
+
+// WriteEntry 实现 EntryWriter 。
+func (s *Syslog) WriteEntry(e *Entry, b []byte) (int, error) {
+	return s.write(e.Level, e.Name, e.Trace, b)
+}
+
+// syslogLevel 从 "[name] [level] " 前缀中解析级别，没有的话是 info
+func syslogLevel(b []byte) Level {
+	for i := 0; i < 2 && len(b) > 3 && b[0] == '['; i++ {
+		if b[2] == ']' && b[3] == ' ' {
+			for l := range levels {
+				if levels[l][1] == b[1] {
+					return Level(l)
+				}
+			}
+		}
+		// 跳过名称
+		j := bytes.Index(b, []byte("] "))
+		if j < 0 {
+			break
+		}
+		b = b[j+2:]
+	}
+	return InfoLevel
+}
+
+// write 编码消息，然后写入连接
+func (s *Syslog) write(level Level, name, trace string, b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return 0, errSyslogClosed
+	}
+	// 去掉换行
+	msg := b
+	for len(msg) > 0 && (msg[len(msg)-1] == '\n' || msg[len(msg)-1] == '\r') {
+		msg = msg[:len(msg)-1]
+	}
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			err = s.dial()
+			if err != nil {
+				return 0, err
+			}
+		}
+		s.buf = s.appendMessage(s.buf[:0], level, name, trace, msg)
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+		_, err = s.conn.Write(s.buf)
+		if err == nil {
+			return len(b), nil
+		}
+		// 重新连接
+		s.conn.Close()
+		s.conn = nil
+	}
+	return 0, err
+}
+
+// dial 创建连接
+func (s *Syslog) dial() error {
+	if s.addr != "" {
+		conn, err := net.DialTimeout(s.network, s.addr, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		s.stream = s.network == "tcp" || s.network == "unix"
+		return nil
+	}
+	// 本地的 syslog
+	var err error
+	for _, addr := range syslogLocalAddrs {
+		for _, network := range []string{"unixgram", "unix"} {
+			if s.network != "" && s.network != network {
+				continue
+			}
+			var conn net.Conn
+			conn, err = net.DialTimeout(network, addr, s.timeout)
+			if err == nil {
+				s.conn = conn
+				s.stream = network == "unix"
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// appendMessage 编码一条 syslog 消息，流式连接加上分帧
+func (s *Syslog) appendMessage(b []byte, level Level, name, trace string, msg []byte) []byte {
+	octet := s.stream && s.octet
+	if octet {
+		// 长度前缀，先留出位置
+		b = append(b, "0000000000 "...)
+	}
+	start := len(b)
+	// PRI
+	severity := 6
+	if level >= 0 && int(level) < len(syslogSeverities) {
+		severity = syslogSeverities[level]
+	}
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(s.facility*8+severity), 10)
+	b = append(b, '>')
+	now := Now()
+	if s.rfc5424 {
+		// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+		b = append(b, "1 "...)
+		b = now.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+		b = append(b, ' ')
+		b = appendSyslogName(b, s.hostname, 255)
+		b = append(b, ' ')
+		b = appendSyslogName(b, s.appName, 48)
+		b = append(b, ' ')
+		b = append(b, s.pid...)
+		b = append(b, ' ')
+		b = appendSyslogName(b, name, 32)
+		// STRUCTURED-DATA
+		if trace != "" {
+			b = append(b, " ["...)
+			b = append(b, s.sdid...)
+			b = append(b, ` id="`...)
+			b = appendSyslogParam(b, trace)
+			b = append(b, `"]`...)
+		} else {
+			b = append(b, " -"...)
+		}
+	} else {
+		// TIMESTAMP HOSTNAME TAG[PID]:
+		b = now.AppendFormat(b, time.Stamp)
+		// unix socket 的 syslog 不需要主机名
+		if s.network == "udp" || s.network == "tcp" {
+			b = append(b, ' ')
+			b = append(b, s.hostname...)
+		}
+		b = append(b, ' ')
+		b = append(b, s.appName...)
+		b = append(b, '[')
+		b = append(b, s.pid...)
+		b = append(b, "]:"...)
+	}
+	b = append(b, ' ')
+	b = append(b, msg...)
+	if octet {
+		// 设置长度前缀
+		n := strconv.Itoa(len(b) - start)
+		prefix := start - len(n) - 1
+		copy(b[prefix:], n)
+		return append(b[:0], b[prefix:]...)
+	}
+	if s.stream {
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// appendSyslogName 写入 rfc5424 头中的名称，空是 "-" ，只保留可打印的 ascii
+func appendSyslogName(b []byte, s string, max int) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	n := 0
+	for i := 0; i < len(s) && n < max; i++ {
+		if s[i] > 32 && s[i] < 127 {
+			b = append(b, s[i])
+			n++
+		}
+	}
+	if n == 0 {
+		return append(b, '-')
+	}
+	return b
+}
+
+// appendSyslogParam 写入 rfc5424 结构化数据的值，转义 '"' ，'\' 和 ']'
+func appendSyslogParam(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\\', ']':
+			b = append(b, '\\')
+		}
+		b = append(b, s[i])
+	}
+	return b
+}
+
+// Close 实现 io.Closer 接口，关闭连接。
+func (s *Syslog) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return errSyslogClosed
+	}
+	s.closed = true
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
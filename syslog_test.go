@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_SyslogLevel(t *testing.T) {
+	for _, c := range []struct {
+		s string
+		l Level
+	}{
+		{"[D] a", DebugLevel},
+		{"[W] a", WarnLevel},
+		{"[my name] [E] a", ErrorLevel},
+		{"[F] a", FatalLevel},
+		{"[abc] a", InfoLevel},
+		{"a", InfoLevel},
+	} {
+		if l := syslogLevel([]byte(c.s)); l != c.l {
+			t.Fatal(c.s, l)
+		}
+	}
+}
+
+func Test_SyslogUnixgram(t *testing.T) {
+	now := Now
+	defer func() { Now = now }()
+	Now = func() time.Time {
+		return time.Date(2023, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	}
+	// unix socket 的路径不能太长
+	dir, err := os.MkdirTemp("", "syslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s, err := NewSyslog(&SyslogConfig{
+		Network:  "unixgram",
+		Addr:     path,
+		Format:   "rfc5424",
+		Facility: "local0",
+		AppName:  "app",
+		Hostname: "host",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	read := func() string {
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+	pid := strconv.Itoa(os.Getpid())
+	lg := NewLogger(s, nil, "svc")
+	lg.ErrorTrace(`a"]`, "hello")
+	if m := read(); m != `<131>1 2023-01-02T03:04:05.006000Z host app `+pid+` svc [trace@32473 id="a\"\]"] [svc] [E] [a"]] hello` {
+		t.Fatal(m)
+	}
+	// 从前缀解析级别
+	s.Write([]byte("[W] world\n"))
+	if m := read(); m != `<132>1 2023-01-02T03:04:05.006000Z host app `+pid+` - - [W] world` {
+		t.Fatal(m)
+	}
+	s.Close()
+	if _, err := s.Write([]byte("closed")); err != errSyslogClosed {
+		t.FailNow()
+	}
+}
+
+func Test_SyslogTCP(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	msgs := make(chan string, 100)
+	accepted := make(chan int, 10)
+	go func() {
+		for n := 0; ; n++ {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- n
+			go func(n int) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					// 长度前缀
+					size, err := r.ReadString(' ')
+					if err != nil {
+						return
+					}
+					i, err := strconv.Atoi(strings.TrimSpace(size))
+					if err != nil {
+						t.Error(size)
+						return
+					}
+					b := make([]byte, i)
+					_, err = io.ReadFull(r, b)
+					if err != nil {
+						return
+					}
+					msgs <- string(b)
+					// 第一个连接收到一条之后关闭
+					if n == 0 {
+						return
+					}
+				}
+			}(n)
+		}
+	}()
+	s, err := NewSyslog(&SyslogConfig{
+		Network:  "tcp",
+		Addr:     lis.Addr().String(),
+		AppName:  "app",
+		Hostname: "host",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	lg := NewLogger(s, nil, "")
+	lg.Debug("first")
+	m := <-msgs
+	if !strings.HasPrefix(m, "<15>") || !strings.HasSuffix(m, " host app["+strconv.Itoa(os.Getpid())+"]: [D] first") {
+		t.Fatal(m)
+	}
+	<-accepted
+	// 连接关闭之后重新连接
+	timeout := time.After(5 * time.Second)
+	for i := 0; ; i++ {
+		lg.Warn("next")
+		select {
+		case <-accepted:
+			lg.Warn("last")
+			for {
+				select {
+				case m = <-msgs:
+					if strings.HasSuffix(m, "[W] last") {
+						if !strings.HasPrefix(m, "<12>") {
+							t.Fatal(m)
+						}
+						return
+					}
+				case <-timeout:
+					t.Fatal("timeout")
+				}
+			}
+		case <-timeout:
+			t.Fatal("timeout")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
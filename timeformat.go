@@ -0,0 +1,188 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 时间格式
+const (
+	timeLayoutDefault = iota
+	timeLayoutRFC3339
+	timeLayoutUnix
+	timeLayoutUnixMilli
+	timeLayoutCustom
+)
+
+var (
+	// Now 是日志使用的时钟，测试的时候可以替换，得到固定的时间
+	Now = time.Now
+	// FormatTime 使用的格式
+	defaultTimeFormatter = &TimeFormatter{loc: time.Local, digits: 9}
+	// 10 的 n 次方
+	pow10 = [...]int{1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000}
+)
+
+// TimeConfig 是 NewTimeFormatter 的参数
+type TimeConfig struct {
+	// 格式，default 是 2006-01-02 15:04:05.000000000 ，rfc3339 是 2006-01-02T15:04:05Z07:00 ，
+	// rfc3339nano 是 rfc3339 加上纳秒，unix 是秒数，unixmilli 是毫秒数，
+	// 其他的当作 time.Format 的 layout ，默认是 default
+	Layout string `json:"layout" yaml:"layout"`
+	// 时区，local/utc 或者 time.LoadLocation 的名称，比如 Asia/Shanghai ，默认是 local
+	Zone string `json:"zone" yaml:"zone"`
+	// 秒的小数部分，s/ms/us/ns ，只有 default 和 rfc3339 使用，
+	// 默认 default 和 rfc3339nano 是 ns ，rfc3339 是 s
+	Precision string `json:"precision" yaml:"precision" validate:"omitempty,oneof=s ms us µs ns"`
+}
+
+// TimeFormatter 用于格式化日志的时间，default 和 rfc3339 不使用 time.Format ，快一点
+type TimeFormatter struct {
+	// 格式
+	layout int
+	// 自定义的 layout
+	custom string
+	// 时区
+	loc *time.Location
+	// 小数的位数
+	digits int
+}
+
+// NewTimeFormatter 返回 TimeFormatter
+func NewTimeFormatter(conf *TimeConfig) (*TimeFormatter, error) {
+	tf := new(TimeFormatter)
+	tf.digits = 9
+	// 格式
+	switch strings.ToLower(conf.Layout) {
+	case "", "default":
+		tf.layout = timeLayoutDefault
+	case "rfc3339":
+		tf.layout = timeLayoutRFC3339
+		tf.digits = 0
+	case "rfc3339nano":
+		tf.layout = timeLayoutRFC3339
+	case "unix":
+		tf.layout = timeLayoutUnix
+	case "unixmilli":
+		tf.layout = timeLayoutUnixMilli
+	default:
+		tf.layout = timeLayoutCustom
+		tf.custom = conf.Layout
+	}
+	// 时区
+	switch strings.ToLower(conf.Zone) {
+	case "", "local":
+		tf.loc = time.Local
+	case "utc":
+		tf.loc = time.UTC
+	default:
+		loc, err := time.LoadLocation(conf.Zone)
+		if err != nil {
+			return nil, err
+		}
+		tf.loc = loc
+	}
+	// 精度
+	switch conf.Precision {
+	case "":
+	case "s":
+		tf.digits = 0
+	case "ms":
+		tf.digits = 3
+	case "us", "µs":
+		tf.digits = 6
+	case "ns":
+		tf.digits = 9
+	default:
+		return nil, fmt.Errorf("unknown time precision %q", conf.Precision)
+	}
+	return tf, nil
+}
+
+// Format 写入 Now 的时间
+func (tf *TimeFormatter) Format(log *Log) {
+	tf.Append(log, Now())
+}
+
+// Append 写入时间 t
+func (tf *TimeFormatter) Append(log *Log, t time.Time) {
+	switch tf.layout {
+	case timeLayoutUnix:
+		log.Int64(t.Unix())
+		return
+	case timeLayoutUnixMilli:
+		log.Int64(t.UnixMilli())
+		return
+	case timeLayoutCustom:
+		log.b = t.In(tf.loc).AppendFormat(log.b, tf.custom)
+		return
+	}
+	t = t.In(tf.loc)
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	// Date
+	log.IntRightAlign(year, 4)
+	log.b = append(log.b, '-')
+	log.IntRightAlign(int(month), 2)
+	log.b = append(log.b, '-')
+	log.IntRightAlign(day, 2)
+	if tf.layout == timeLayoutRFC3339 {
+		log.b = append(log.b, 'T')
+	} else {
+		log.b = append(log.b, ' ')
+	}
+	// Time
+	log.IntRightAlign(hour, 2)
+	log.b = append(log.b, ':')
+	log.IntRightAlign(minute, 2)
+	log.b = append(log.b, ':')
+	log.IntRightAlign(second, 2)
+	// 小数
+	if tf.digits > 0 {
+		log.b = append(log.b, '.')
+		log.IntRightAlign(t.Nanosecond()/pow10[9-tf.digits], tf.digits)
+	}
+	// 时区
+	if tf.layout == timeLayoutRFC3339 {
+		_, offset := t.Zone()
+		if offset == 0 {
+			log.b = append(log.b, 'Z')
+			return
+		}
+		if offset < 0 {
+			log.b = append(log.b, '-')
+			offset = -offset
+		} else {
+			log.b = append(log.b, '+')
+		}
+		offset /= 60
+		log.IntRightAlign(offset/60, 2)
+		log.b = append(log.b, ':')
+		log.IntRightAlign(offset%60, 2)
+	}
+}
+
+// epoch 返回是否输出数字
+func (tf *TimeFormatter) epoch() bool {
+	return tf.layout == timeLayoutUnix || tf.layout == timeLayoutUnixMilli
+}
+
+// Header 是 FormatHeader ，输出时间
+func (tf *TimeFormatter) Header(log *Log, depth int) {
+	tf.Format(log)
+}
+
+// FileNameHeader 是 FormatHeader ，输出时间 fileName:fileLine
+func (tf *TimeFormatter) FileNameHeader(log *Log, depth int) {
+	tf.Format(log)
+	log.b = append(log.b, ' ')
+	writeCaller(log, depth+1, false)
+}
+
+// FilePathHeader 是 FormatHeader ，输出时间 filePath:fileLine
+func (tf *TimeFormatter) FilePathHeader(log *Log, depth int) {
+	tf.Format(log)
+	log.b = append(log.b, ' ')
+	writeCaller(log, depth+1, true)
+}
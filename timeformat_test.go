@@ -0,0 +1,80 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_TimeFormatter(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 1234567, time.UTC)
+	for _, c := range []struct {
+		conf TimeConfig
+		s    string
+	}{
+		{TimeConfig{Zone: "utc"}, "2023-01-02 03:04:05.001234567"},
+		{TimeConfig{Zone: "utc", Precision: "ms"}, "2023-01-02 03:04:05.001"},
+		{TimeConfig{Zone: "utc", Precision: "s"}, "2023-01-02 03:04:05"},
+		{TimeConfig{Layout: "rfc3339", Zone: "utc"}, "2023-01-02T03:04:05Z"},
+		{TimeConfig{Layout: "rfc3339nano", Zone: "utc"}, "2023-01-02T03:04:05.001234567Z"},
+		{TimeConfig{Layout: "rfc3339", Zone: "Asia/Shanghai", Precision: "us"}, "2023-01-02T11:04:05.001234+08:00"},
+		{TimeConfig{Layout: "rfc3339", Zone: "America/New_York"}, "2023-01-01T22:04:05-05:00"},
+		{TimeConfig{Layout: "unix"}, "1672628645"},
+		{TimeConfig{Layout: "unixmilli"}, "1672628645001"},
+		{TimeConfig{Layout: "15:04:05.000", Zone: "utc"}, "03:04:05.001"},
+	} {
+		tf, err := NewTimeFormatter(&c.conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var l Log
+		tf.Append(&l, now)
+		if string(l.b) != c.s {
+			t.Fatal(c.conf, string(l.b))
+		}
+	}
+	// 错误
+	if _, err := NewTimeFormatter(&TimeConfig{Zone: "no/zone"}); err == nil {
+		t.FailNow()
+	}
+	if _, err := NewTimeFormatter(&TimeConfig{Precision: "m"}); err == nil {
+		t.FailNow()
+	}
+}
+
+func Test_TimeFormatterHeader(t *testing.T) {
+	now := Now
+	defer func() { Now = now }()
+	Now = func() time.Time {
+		return time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+	}
+	tf, err := NewTimeFormatter(&TimeConfig{Layout: "rfc3339", Zone: "utc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var l Log
+	lg := NewLogger(&l, tf.FileNameHeader, "")
+	lg.Info("a")
+	if !strings.HasPrefix(string(l.b), "[I] 2023-01-02T03:04:05Z timeformat_test.go:") {
+		t.Fatal(string(l.b))
+	}
+	// json
+	l.Reset()
+	lg = NewLogger(&l, nil, "")
+	lg.Encoder = &JSONEncoder{Time: tf}
+	lg.Info("a")
+	if string(l.b) != `{"time":"2023-01-02T03:04:05Z","level":"info","msg":"a"}`+"\n" {
+		t.Fatal(string(l.b))
+	}
+	// 自定义的格式需要转义
+	tf, err = NewTimeFormatter(&TimeConfig{Layout: `2006"01\02`, Zone: "utc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Reset()
+	lg.Encoder = &JSONEncoder{Time: tf}
+	lg.Info("a")
+	if string(l.b) != `{"time":"2023\"01\\02","level":"info","msg":"a"}`+"\n" {
+		t.Fatal(string(l.b))
+	}
+}
@@ -3,6 +3,7 @@ package log
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -74,3 +75,14 @@ func parseSize(str, unit string, bytes int64) (int64, error) {
 	}
 	return -1, nil
 }
+
+// stopTimer 停止 t ，并且取出已经到期的值，这样 Reset 之后不会收到旧的值。
+// 只能在读取 t.C 的协程中调用。
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
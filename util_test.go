@@ -1,6 +1,9 @@
 package log
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func Test_ParseSize(t *testing.T) {
 	for _, s := range []struct {
@@ -26,3 +29,17 @@ func Test_ParseSize(t *testing.T) {
 		}
 	}
 }
+
+func Test_StopTimer(t *testing.T) {
+	timer := time.NewTimer(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	// 已经到期的值被取出
+	stopTimer(timer)
+	timer.Reset(time.Hour)
+	select {
+	case <-timer.C:
+		t.FailNow()
+	case <-time.After(10 * time.Millisecond):
+	}
+	stopTimer(timer)
+}
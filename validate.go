@@ -0,0 +1,169 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError 是配置中一个字段的错误
+type FieldError struct {
+	// 字段的路径，比如 loggers[0].outputs[1].file.rootDir
+	Field string
+	// 没有通过的规则，比如 required ，min=1
+	Rule string
+	// 字段的值
+	Value any
+}
+
+// Error 实现 error
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: value %v does not satisfy %q", e.Field, e.Value, e.Rule)
+}
+
+// ValidationErrors 是配置中所有字段的错误
+type ValidationErrors []*FieldError
+
+// Error 实现 error
+func (e ValidationErrors) Error() string {
+	var s strings.Builder
+	for i, fe := range e {
+		if i > 0 {
+			s.WriteString("; ")
+		}
+		s.WriteString(fe.Error())
+	}
+	return s.String()
+}
+
+// validateConfig 使用结构体的 validate 标签检查 v ，字段的名称使用 json 标签，
+// 只支持这个库用到的 required ，required_without ，omitempty ，min ，oneof ，dirpath 和 dive ，
+// dive 的元素是 nil 的时候是 required 的错误。
+// 规则按照顺序检查，遇到第一个不通过的停止，omitempty 在值为空的时候跳过后面所有的规则，
+// 所以它可以放在需要检查空值的规则后面，比如 "required_without=Path,omitempty,dirpath" ，
+// 为空的时候只检查 required_without ，不为空的时候再检查 dirpath 。
+func validateConfig(v any) error {
+	var errs ValidationErrors
+	validateValue(&errs, "", reflect.ValueOf(v))
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateValue 检查结构体，其他类型忽略
+func validateValue(errs *ValidationErrors, path string, v reflect.Value) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" {
+			name = sf.Name
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+		validateField(errs, name, v, v.Field(i), sf.Tag.Get("validate"))
+	}
+}
+
+// validateField 检查结构体 parent 的一个字段 v
+func validateField(errs *ValidationErrors, path string, parent, v reflect.Value, tag string) {
+	dive := false
+	if tag != "" {
+		for _, rule := range strings.Split(tag, ",") {
+			if rule == "omitempty" {
+				if v.IsZero() {
+					return
+				}
+				continue
+			}
+			if rule == "dive" {
+				dive = true
+				break
+			}
+			if !checkRule(parent, v, rule) {
+				*errs = append(*errs, &FieldError{Field: path, Rule: rule, Value: fieldValue(v)})
+				return
+			}
+		}
+	}
+	// 数组的元素
+	if dive || v.Kind() == reflect.Slice {
+		if v.Kind() == reflect.Slice {
+			for i := 0; i < v.Len(); i++ {
+				p, e := path+"["+strconv.Itoa(i)+"]", v.Index(i)
+				// dive 的元素不能是 nil
+				if dive && e.Kind() == reflect.Pointer && e.IsNil() {
+					*errs = append(*errs, &FieldError{Field: p, Rule: "required"})
+					continue
+				}
+				validateValue(errs, p, e)
+			}
+		}
+		return
+	}
+	validateValue(errs, path, v)
+}
+
+// fieldValue 返回用于错误信息的值
+func fieldValue(v reflect.Value) any {
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Pointer || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// checkRule 返回结构体 parent 的字段 v 是否符合 rule
+func checkRule(parent, v reflect.Value, rule string) bool {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		return !v.IsZero()
+	case "required_without":
+		// arg 字段为空的时候是 required
+		other := parent.FieldByName(arg)
+		return !v.IsZero() || (other.IsValid() && !other.IsZero())
+	case "min":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return false
+		}
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map:
+			return float64(v.Len()) >= n
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(v.Int()) >= n
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(v.Uint()) >= n
+		case reflect.Float32, reflect.Float64:
+			return v.Float() >= n
+		}
+	case "oneof":
+		s := fmt.Sprint(fieldValue(v))
+		for _, o := range strings.Fields(arg) {
+			if o == s {
+				return true
+			}
+		}
+		return false
+	}
+	// dirpath 等其他规则，在打开的时候检查
+	return true
+}